@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/goccy/go-yaml"
+)
+
+// Files collects every config file loaded while parsing so they can be
+// inspected later (ex: by "config print"). Later files take precedence.
+type Files struct {
+	resolvers []*Resolver
+}
+
+// Load is a kong.ConfigurationLoader.
+func (files *Files) Load(reader io.Reader) (kong.Resolver, error) {
+	resolver, err := Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	files.resolvers = append(files.resolvers, resolver)
+	return resolver, nil
+}
+
+func (files *Files) Lookup(node *kong.Node, name string) (any, bool) {
+	for _, resolver := range slices.Backward(files.resolvers) {
+		if value, ok := resolver.Lookup(node, name); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Resolver resolves flags from a YAML (or JSON) document. Flags are looked up
+// in the section of their command first, then each parent section:
+//
+//	level: debug
+//	serve:
+//	  interval: 30m
+//	notify:
+//	  discord:
+//	    dry-run: true
+//
+// Flag names may use dashes or underscores.
+type Resolver struct {
+	values map[string]any
+}
+
+var _ kong.Resolver = &Resolver{}
+
+func Parse(reader io.Reader) (*Resolver, error) {
+	values := map[string]any{}
+	if err := yaml.NewDecoder(reader).Decode(&values); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	return &Resolver{values: values}, nil
+}
+
+func (resolver *Resolver) Resolve(_ *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	value, _ := resolver.Lookup(parent.Node(), flag.Name)
+	return value, nil
+}
+
+func (resolver *Resolver) Lookup(node *kong.Node, name string) (any, bool) {
+	sections := Sections(node)
+	for i := len(sections); i >= 0; i-- {
+		values, ok := descend(resolver.values, sections[:i])
+		if !ok {
+			continue
+		}
+		for _, key := range []string{name, strings.ReplaceAll(name, "-", "_")} {
+			if value, ok := values[key]; ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Validate reports keys that do not match any command or flag.
+func (resolver *Resolver) Validate(app *kong.Application) error {
+	return validate(app.Node, resolver.values, "")
+}
+
+func validate(node *kong.Node, values map[string]any, prefix string) error {
+	var errs []error
+	for key, value := range values {
+		name := strings.ReplaceAll(key, "_", "-")
+		if child := command(node, name); child != nil {
+			if section, ok := value.(map[string]any); ok {
+				errs = append(errs, validate(child, section, prefix+key+"."))
+				continue
+			}
+		}
+		if hasFlag(node, name) {
+			continue
+		}
+		if arg := findArg(node, name); arg != nil && len(arg.Tag.Envs) > 0 {
+			errs = append(errs, fmt.Errorf("%s%s: positional arguments can not be set from a config file, use $%s", prefix, key, arg.Tag.Envs[0]))
+		} else {
+			errs = append(errs, fmt.Errorf("%s%s: unknown key", prefix, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sections returns the names of the commands leading to node.
+func Sections(node *kong.Node) []string {
+	var sections []string
+	for ; node != nil; node = node.Parent {
+		if node.Type == kong.CommandNode {
+			sections = append(sections, node.Name)
+		}
+	}
+	slices.Reverse(sections)
+	return sections
+}
+
+func descend(values map[string]any, sections []string) (map[string]any, bool) {
+	for _, section := range sections {
+		next, ok := values[section].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		values = next
+	}
+	return values, true
+}
+
+func command(node *kong.Node, name string) *kong.Node {
+	for _, child := range node.Children {
+		if child.Type == kong.CommandNode && child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// hasFlag reports if node or any of its commands has the flag, since a key in a
+// parent section applies to all of them.
+func hasFlag(node *kong.Node, name string) bool {
+	for _, flag := range node.Flags {
+		if flag.Name == name {
+			return true
+		}
+	}
+	for _, child := range node.Children {
+		if hasFlag(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func findArg(node *kong.Node, name string) *kong.Value {
+	for _, arg := range node.Positional {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	for _, child := range node.Children {
+		if arg := findArg(child, name); arg != nil {
+			return arg
+		}
+	}
+	return nil
+}
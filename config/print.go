@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/goccy/go-yaml"
+)
+
+// Redacted replaces the value of any flag or argument tagged `secret:""`.
+const Redacted = "REDACTED"
+
+type CMD struct {
+	Print PrintCMD `cmd:"" help:"Print the effective configuration with secrets redacted."`
+}
+
+type PrintCMD struct{}
+
+func (cmd *PrintCMD) Run(kctx *kong.Context, files *Files, stdout io.Writer) error {
+	effective := Effective(kctx.Model.Node, files)
+	data, err := yaml.MarshalWithOptions(effective, yaml.IndentSequence(true))
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+// Effective returns the value of every flag and argument under node after
+// merging command line, config files, environment and defaults.
+func Effective(node *kong.Node, files *Files) yaml.MapSlice {
+	var out yaml.MapSlice
+	for _, flag := range node.Flags {
+		if flag.Name == "help" || flag.Target.Type() == reflect.TypeFor[kong.ConfigFlag]() {
+			continue
+		}
+		// Resolvers are only applied to the commands being run.
+		value, ok := files.Lookup(node, flag.Name)
+		if flag.Active || !ok {
+			value = format(flag.Target)
+		}
+		out = append(out, yaml.MapItem{Key: flag.Name, Value: redact(flag.Value, value)})
+	}
+	for _, arg := range node.Positional {
+		if arg.Tag.Type == "filecontent" {
+			continue
+		}
+		out = append(out, yaml.MapItem{Key: arg.Name, Value: redact(arg, format(arg.Target))})
+	}
+	for _, child := range node.Children {
+		if child.Type != kong.CommandNode {
+			continue
+		}
+		if section := Effective(child, files); len(section) > 0 {
+			out = append(out, yaml.MapItem{Key: child.Name, Value: section})
+		}
+	}
+	return out
+}
+
+func redact(value *kong.Value, current any) any {
+	if !value.Tag.Has("secret") || current == nil || reflect.ValueOf(current).IsZero() {
+		return current
+	}
+	return Redacted
+}
+
+func format(value reflect.Value) any {
+	switch v := value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(text)
+	default:
+		return v
+	}
+}
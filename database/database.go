@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ohhfishal/fishy/flashcard"
 	_ "modernc.org/sqlite"
-	"os"
+	"slices"
+	"time"
 )
 
 //go:embed schema.sql
@@ -34,51 +36,251 @@ func Connect(ctx context.Context, driver string, connection string) (*Store, err
 	}, nil
 }
 
+// upgrade adds a column to a table created by an older version of schema.sql.
+// SQL may also rebuild the table when a column can't simply be added (ex: it
+// is part of the primary key).
+type upgrade struct {
+	Table  string
+	Column string
+	SQL    string
+}
+
+// NOTE: Keep in sync with schema.sql, which is what new databases get.
+var upgrades = []upgrade{
+	{
+		Table:  "jobs",
+		Column: "deck",
+		SQL:    "ALTER TABLE jobs ADD COLUMN deck TEXT NOT NULL DEFAULT ''",
+	},
+	{
+		Table:  "flashcards",
+		Column: "textbook",
+		SQL:    rebuildFlashcards,
+	},
+	{
+		Table:  "flashcards",
+		Column: "language",
+		SQL:    "ALTER TABLE flashcards ADD COLUMN language TEXT NOT NULL DEFAULT ''",
+	},
+	{
+		Table:  "flashcards",
+		Column: "kind",
+		SQL:    "ALTER TABLE flashcards ADD COLUMN kind TEXT NOT NULL DEFAULT ''",
+	},
+	{
+		Table:  "flashcards",
+		Column: "reveal",
+		SQL:    "ALTER TABLE flashcards ADD COLUMN reveal TEXT",
+	},
+	{
+		Table:  "flashcards",
+		Column: "direction",
+		SQL:    rebuildFlashcardsDirection,
+	},
+	{
+		Table:  "flashcards",
+		Column: "style",
+		SQL:    "ALTER TABLE flashcards ADD COLUMN style TEXT",
+	},
+}
+
+// rebuildFlashcards adds the textbook columns to the primary key, moving the
+// chapter out of class_context ("Chapter: %d") where it used to be.
+const rebuildFlashcards = `
+BEGIN;
+CREATE TABLE flashcards_upgrade (
+  header TEXT NOT NULL,
+  description TEXT NOT NULL,
+  origin TEXT NOT NULL,
+  class_context TEXT NOT NULL,
+  ai_overview TEXT,
+  thumbnail TEXT,
+  textbook TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL DEFAULT '',
+  chapter INTEGER NOT NULL DEFAULT 0,
+  chapter_title TEXT NOT NULL DEFAULT '',
+
+  PRIMARY KEY (header, origin, textbook, chapter, class_context)
+);
+INSERT INTO flashcards_upgrade (header, description, origin, class_context, ai_overview, thumbnail, chapter)
+SELECT
+  header,
+  description,
+  origin,
+  CASE WHEN class_context GLOB 'Chapter: [1-9]*' AND class_context NOT GLOB 'Chapter: *[^0-9]*' THEN '' ELSE class_context END,
+  ai_overview,
+  thumbnail,
+  CASE WHEN class_context GLOB 'Chapter: [1-9]*' AND class_context NOT GLOB 'Chapter: *[^0-9]*' THEN CAST(substr(class_context, 10) AS INTEGER) ELSE 0 END
+FROM flashcards;
+DROP TABLE flashcards;
+ALTER TABLE flashcards_upgrade RENAME TO flashcards;
+COMMIT;
+`
+
+// rebuildFlashcardsDirection adds direction to the primary key so a term's
+// forward and reverse cards are both kept.
+const rebuildFlashcardsDirection = `
+BEGIN;
+CREATE TABLE flashcards_upgrade (
+  header TEXT NOT NULL,
+  description TEXT NOT NULL,
+  origin TEXT NOT NULL,
+  class_context TEXT NOT NULL,
+  ai_overview TEXT,
+  thumbnail TEXT,
+  textbook TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL DEFAULT '',
+  chapter INTEGER NOT NULL DEFAULT 0,
+  chapter_title TEXT NOT NULL DEFAULT '',
+  language TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL DEFAULT '',
+  reveal TEXT,
+  direction TEXT NOT NULL DEFAULT '',
+
+  PRIMARY KEY (header, origin, textbook, chapter, class_context, direction)
+);
+INSERT INTO flashcards_upgrade (header, description, origin, class_context, ai_overview, thumbnail, textbook, subject, chapter, chapter_title, language, kind, reveal)
+SELECT header, description, origin, class_context, ai_overview, thumbnail, textbook, subject, chapter, chapter_title, language, kind, reveal
+FROM flashcards;
+DROP TABLE flashcards;
+ALTER TABLE flashcards_upgrade RENAME TO flashcards;
+COMMIT;
+`
+
 func RunMigrations(ctx context.Context, db DBTX) error {
+	// Upgrade existing tables first so the schema can reference new columns.
+	for _, upgrade := range upgrades {
+		if err := runUpgrade(ctx, db, upgrade); err != nil {
+			return fmt.Errorf("upgrading %s.%s: %w", upgrade.Table, upgrade.Column, err)
+		}
+	}
 	if _, err := db.ExecContext(ctx, schema); err != nil {
 		return err
 	}
 	return nil
 }
 
+func runUpgrade(ctx context.Context, db DBTX, upgrade upgrade) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", upgrade.Table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return err
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Table does not exist yet or is already up to date.
+	if len(columns) == 0 || slices.Contains(columns, upgrade.Column) {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, upgrade.SQL)
+	return err
+}
+
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Ping checks that the database is still reachable.
+func (store *Store) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// TryLease takes (or renews) the named lease for holder until ttl passes.
+// It returns false if someone else holds an unexpired lease.
+func (store *Store) TryLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := store.AcquireLease(ctx, AcquireLeaseParams{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: now.Add(ttl).UnixMilli(),
+		Now:       now.UnixMilli(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// LoadFlashcards inserts cards, ignoring any already in the store. It returns
+// how many were inserted.
 func (store *Store) LoadFlashcards(ctx context.Context, cards []flashcard.Flashcard) (int, error) {
+	duplicates, err := store.InsertFlashcards(ctx, cards)
+	if err != nil {
+		return -1, err
+	}
+	return len(cards) - len(duplicates), nil
+}
+
+// InsertFlashcards inserts cards in a single transaction and returns the
+// indexes of cards skipped because they were already in the store.
+func (store *Store) InsertFlashcards(ctx context.Context, cards []flashcard.Flashcard) ([]int, error) {
 	tx, err := store.db.Begin()
 	if err != nil {
-		return -1, fmt.Errorf("starting transaction: %w", err)
+		return nil, fmt.Errorf("starting transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	qtx := store.WithTx(tx)
-	i := 0
-	for _, card := range cards {
-		if _, err := qtx.InsertCard(ctx, InsertCardParams{
+	var duplicates []int
+	for i, card := range cards {
+		_, err := qtx.InsertCard(ctx, InsertCardParams{
 			Header:       card.Header,
 			Description:  card.Description,
 			Origin:       card.Origin,
 			ClassContext: card.ClassContext,
 			AiOverview:   card.AIOverview,
 			Thumbnail:    card.Thumbnail,
-		}); err != nil {
-			return -1, err
+			Textbook:     card.Textbook,
+			Subject:      card.Subject,
+			Chapter:      int64(card.Chapter),
+			ChapterTitle: card.ChapterTitle,
+			Language:     card.Language,
+			Kind:         card.Kind,
+			Reveal:       card.Reveal,
+			Direction:    card.Direction,
+			Style:        card.Style,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			duplicates = append(duplicates, i)
+		} else if err != nil {
+			return nil, err
 		}
-		i++
 	}
-	return i, tx.Commit()
+	return duplicates, tx.Commit()
 }
 
 func (store *Store) LoadFlashcardsFrom(ctx context.Context, filepath string) (int, error) {
-	file, err := os.Open(filepath)
+	flashcards, err := flashcard.ReadFlashcards(filepath)
 	if err != nil {
-		return -1, fmt.Errorf("opening file: %w", err)
+		return -1, err
 	}
-	defer file.Close()
+	return store.LoadFlashcards(ctx, flashcards)
+}
 
-	var flashcards []flashcard.Flashcard
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&flashcards); err != nil {
-		return -1, fmt.Errorf("parsing file: %w", err)
+// Flashcards returns every card in the store.
+func (store *Store) Flashcards(ctx context.Context) ([]flashcard.Flashcard, error) {
+	rows, err := store.GetCards(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return store.LoadFlashcards(ctx, flashcards)
+	cards := make([]flashcard.Flashcard, 0, len(rows))
+	for _, row := range rows {
+		cards = append(cards, ConvertFlashcard(row))
+	}
+	return cards, nil
 }
 
 // NOTE: This funcction must always work or there is a bug in our types
@@ -10,6 +10,11 @@ import (
 	"github.com/ohhfishal/fishy/flashcard"
 )
 
+type DeckRole struct {
+	Deck string `json:"deck"`
+	Role string `json:"role"`
+}
+
 type Flashcard struct {
 	Header       string          `json:"header"`
 	Description  string          `json:"description"`
@@ -17,10 +22,33 @@ type Flashcard struct {
 	ClassContext string          `json:"class_context"`
 	AiOverview   StringArray     `json:"ai_overview"`
 	Thumbnail    flashcard.Image `json:"thumbnail"`
+	Textbook     string          `json:"textbook"`
+	Subject      string          `json:"subject"`
+	Chapter      int64           `json:"chapter"`
+	ChapterTitle string          `json:"chapter_title"`
+	Language     string          `json:"language"`
+	Kind         string          `json:"kind"`
+	Reveal       flashcard.Image `json:"reveal"`
+	Direction    string          `json:"direction"`
+	Style        flashcard.Style `json:"style"`
 }
 
 type Job struct {
 	ID        int64     `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	Failures  int64     `json:"failures"`
+	Deck      string    `json:"deck"`
+}
+
+type Lease struct {
+	Name      string `json:"name"`
+	Holder    string `json:"holder"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type Thread struct {
+	Deck     string `json:"deck"`
+	Webhook  string `json:"webhook"`
+	Day      string `json:"day"`
+	ThreadID string `json:"thread_id"`
 }
@@ -1,12 +1,14 @@
 -- name: GetLastJob :many
 SELECT * FROM jobs
+WHERE deck = ?
 ORDER BY created_at DESC
 LIMIT 1;
 
 -- name: PutJob :one
 INSERT INTO jobs (
+  deck,
   failures
-) values (?) 
+) values (?, ?)
 RETURNING *;
 
 -- name: GetCards :many
@@ -19,11 +21,76 @@ INSERT INTO flashcards (
   origin,
   class_context,
   ai_overview,
-  thumbnail
-) values (?, ?, ?, ?, ?, ?)
+  thumbnail,
+  textbook,
+  subject,
+  chapter,
+  chapter_title,
+  language,
+  kind,
+  reveal,
+  direction,
+  style
+) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING
 RETURNING *;
 
 -- name: Metrics :one
 SELECT
   (SELECT COUNT(*) FROM jobs) as jobs,
   (SELECT COUNT(*) FROM flashcards) as flashcards;
+
+-- name: CountCards :many
+SELECT textbook, chapter, COUNT(*) as cards FROM flashcards
+GROUP BY textbook, chapter
+ORDER BY textbook, chapter;
+
+-- name: AcquireLease :one
+INSERT INTO leases (
+  name,
+  holder,
+  expires_at
+) values (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET
+  holder = excluded.holder,
+  expires_at = excluded.expires_at
+WHERE leases.holder = excluded.holder OR leases.expires_at < sqlc.arg(now)
+RETURNING *;
+
+-- name: ReleaseLease :exec
+DELETE FROM leases
+WHERE name = ? AND holder = ?;
+
+-- name: GetDeckRoles :many
+SELECT role FROM deck_roles
+WHERE deck = ?
+ORDER BY role;
+
+-- name: ListDeckRoles :many
+SELECT * FROM deck_roles
+ORDER BY deck, role;
+
+-- name: AddDeckRole :exec
+INSERT INTO deck_roles (
+  deck,
+  role
+) values (?, ?)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveDeckRole :execrows
+DELETE FROM deck_roles
+WHERE deck = ? AND role = ?;
+
+-- name: GetThread :many
+SELECT thread_id FROM threads
+WHERE deck = ? AND webhook = ? AND day = ?;
+
+-- name: PutThread :exec
+INSERT INTO threads (
+  deck,
+  webhook,
+  day,
+  thread_id
+) values (?, ?, ?, ?)
+ON CONFLICT (deck, webhook, day) DO UPDATE SET
+  thread_id = excluded.thread_id;
@@ -11,8 +11,93 @@ import (
 	"github.com/ohhfishal/fishy/flashcard"
 )
 
+const acquireLease = `-- name: AcquireLease :one
+INSERT INTO leases (
+  name,
+  holder,
+  expires_at
+) values (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET
+  holder = excluded.holder,
+  expires_at = excluded.expires_at
+WHERE leases.holder = excluded.holder OR leases.expires_at < ?
+RETURNING name, holder, expires_at
+`
+
+type AcquireLeaseParams struct {
+	Name      string `json:"name"`
+	Holder    string `json:"holder"`
+	ExpiresAt int64  `json:"expires_at"`
+	Now       int64  `json:"now"`
+}
+
+func (q *Queries) AcquireLease(ctx context.Context, arg AcquireLeaseParams) (Lease, error) {
+	row := q.db.QueryRowContext(ctx, acquireLease,
+		arg.Name,
+		arg.Holder,
+		arg.ExpiresAt,
+		arg.Now,
+	)
+	var i Lease
+	err := row.Scan(&i.Name, &i.Holder, &i.ExpiresAt)
+	return i, err
+}
+
+const addDeckRole = `-- name: AddDeckRole :exec
+INSERT INTO deck_roles (
+  deck,
+  role
+) values (?, ?)
+ON CONFLICT DO NOTHING
+`
+
+type AddDeckRoleParams struct {
+	Deck string `json:"deck"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) AddDeckRole(ctx context.Context, arg AddDeckRoleParams) error {
+	_, err := q.db.ExecContext(ctx, addDeckRole, arg.Deck, arg.Role)
+	return err
+}
+
+const countCards = `-- name: CountCards :many
+SELECT textbook, chapter, COUNT(*) as cards FROM flashcards
+GROUP BY textbook, chapter
+ORDER BY textbook, chapter
+`
+
+type CountCardsRow struct {
+	Textbook string `json:"textbook"`
+	Chapter  int64  `json:"chapter"`
+	Cards    int64  `json:"cards"`
+}
+
+func (q *Queries) CountCards(ctx context.Context) ([]CountCardsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countCards)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountCardsRow
+	for rows.Next() {
+		var i CountCardsRow
+		if err := rows.Scan(&i.Textbook, &i.Chapter, &i.Cards); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCards = `-- name: GetCards :many
-SELECT header, description, origin, class_context, ai_overview, thumbnail FROM flashcards
+SELECT header, description, origin, class_context, ai_overview, thumbnail, textbook, subject, chapter, chapter_title, language, kind, reveal, direction, style FROM flashcards
 `
 
 func (q *Queries) GetCards(ctx context.Context) ([]Flashcard, error) {
@@ -31,6 +116,15 @@ func (q *Queries) GetCards(ctx context.Context) ([]Flashcard, error) {
 			&i.ClassContext,
 			&i.AiOverview,
 			&i.Thumbnail,
+			&i.Textbook,
+			&i.Subject,
+			&i.Chapter,
+			&i.ChapterTitle,
+			&i.Language,
+			&i.Kind,
+			&i.Reveal,
+			&i.Direction,
+			&i.Style,
 		); err != nil {
 			return nil, err
 		}
@@ -45,14 +139,44 @@ func (q *Queries) GetCards(ctx context.Context) ([]Flashcard, error) {
 	return items, nil
 }
 
+const getDeckRoles = `-- name: GetDeckRoles :many
+SELECT role FROM deck_roles
+WHERE deck = ?
+ORDER BY role
+`
+
+func (q *Queries) GetDeckRoles(ctx context.Context, deck string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getDeckRoles, deck)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		items = append(items, role)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLastJob = `-- name: GetLastJob :many
-SELECT id, created_at, failures FROM jobs
+SELECT id, created_at, failures, deck FROM jobs
+WHERE deck = ?
 ORDER BY created_at DESC
 LIMIT 1
 `
 
-func (q *Queries) GetLastJob(ctx context.Context) ([]Job, error) {
-	rows, err := q.db.QueryContext(ctx, getLastJob)
+func (q *Queries) GetLastJob(ctx context.Context, deck string) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, getLastJob, deck)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +184,12 @@ func (q *Queries) GetLastJob(ctx context.Context) ([]Job, error) {
 	var items []Job
 	for rows.Next() {
 		var i Job
-		if err := rows.Scan(&i.ID, &i.CreatedAt, &i.Failures); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Failures,
+			&i.Deck,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -74,6 +203,40 @@ func (q *Queries) GetLastJob(ctx context.Context) ([]Job, error) {
 	return items, nil
 }
 
+const getThread = `-- name: GetThread :many
+SELECT thread_id FROM threads
+WHERE deck = ? AND webhook = ? AND day = ?
+`
+
+type GetThreadParams struct {
+	Deck    string `json:"deck"`
+	Webhook string `json:"webhook"`
+	Day     string `json:"day"`
+}
+
+func (q *Queries) GetThread(ctx context.Context, arg GetThreadParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, arg.Deck, arg.Webhook, arg.Day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var thread_id string
+		if err := rows.Scan(&thread_id); err != nil {
+			return nil, err
+		}
+		items = append(items, thread_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const insertCard = `-- name: InsertCard :one
 INSERT INTO flashcards (
   header,
@@ -81,9 +244,19 @@ INSERT INTO flashcards (
   origin,
   class_context,
   ai_overview,
-  thumbnail
-) values (?, ?, ?, ?, ?, ?)
-RETURNING header, description, origin, class_context, ai_overview, thumbnail
+  thumbnail,
+  textbook,
+  subject,
+  chapter,
+  chapter_title,
+  language,
+  kind,
+  reveal,
+  direction,
+  style
+) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING
+RETURNING header, description, origin, class_context, ai_overview, thumbnail, textbook, subject, chapter, chapter_title, language, kind, reveal, direction, style
 `
 
 type InsertCardParams struct {
@@ -93,6 +266,15 @@ type InsertCardParams struct {
 	ClassContext string          `json:"class_context"`
 	AiOverview   StringArray     `json:"ai_overview"`
 	Thumbnail    flashcard.Image `json:"thumbnail"`
+	Textbook     string          `json:"textbook"`
+	Subject      string          `json:"subject"`
+	Chapter      int64           `json:"chapter"`
+	ChapterTitle string          `json:"chapter_title"`
+	Language     string          `json:"language"`
+	Kind         string          `json:"kind"`
+	Reveal       flashcard.Image `json:"reveal"`
+	Direction    string          `json:"direction"`
+	Style        flashcard.Style `json:"style"`
 }
 
 func (q *Queries) InsertCard(ctx context.Context, arg InsertCardParams) (Flashcard, error) {
@@ -103,6 +285,15 @@ func (q *Queries) InsertCard(ctx context.Context, arg InsertCardParams) (Flashca
 		arg.ClassContext,
 		arg.AiOverview,
 		arg.Thumbnail,
+		arg.Textbook,
+		arg.Subject,
+		arg.Chapter,
+		arg.ChapterTitle,
+		arg.Language,
+		arg.Kind,
+		arg.Reveal,
+		arg.Direction,
+		arg.Style,
 	)
 	var i Flashcard
 	err := row.Scan(
@@ -112,10 +303,47 @@ func (q *Queries) InsertCard(ctx context.Context, arg InsertCardParams) (Flashca
 		&i.ClassContext,
 		&i.AiOverview,
 		&i.Thumbnail,
+		&i.Textbook,
+		&i.Subject,
+		&i.Chapter,
+		&i.ChapterTitle,
+		&i.Language,
+		&i.Kind,
+		&i.Reveal,
+		&i.Direction,
+		&i.Style,
 	)
 	return i, err
 }
 
+const listDeckRoles = `-- name: ListDeckRoles :many
+SELECT deck, role FROM deck_roles
+ORDER BY deck, role
+`
+
+func (q *Queries) ListDeckRoles(ctx context.Context) ([]DeckRole, error) {
+	rows, err := q.db.QueryContext(ctx, listDeckRoles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeckRole
+	for rows.Next() {
+		var i DeckRole
+		if err := rows.Scan(&i.Deck, &i.Role); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const metrics = `-- name: Metrics :one
 SELECT
   (SELECT COUNT(*) FROM jobs) as jobs,
@@ -136,14 +364,86 @@ func (q *Queries) Metrics(ctx context.Context) (MetricsRow, error) {
 
 const putJob = `-- name: PutJob :one
 INSERT INTO jobs (
+  deck,
   failures
-) values (?) 
-RETURNING id, created_at, failures
+) values (?, ?)
+RETURNING id, created_at, failures, deck
 `
 
-func (q *Queries) PutJob(ctx context.Context, failures int64) (Job, error) {
-	row := q.db.QueryRowContext(ctx, putJob, failures)
+type PutJobParams struct {
+	Deck     string `json:"deck"`
+	Failures int64  `json:"failures"`
+}
+
+func (q *Queries) PutJob(ctx context.Context, arg PutJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, putJob, arg.Deck, arg.Failures)
 	var i Job
-	err := row.Scan(&i.ID, &i.CreatedAt, &i.Failures)
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Failures,
+		&i.Deck,
+	)
 	return i, err
 }
+
+const putThread = `-- name: PutThread :exec
+INSERT INTO threads (
+  deck,
+  webhook,
+  day,
+  thread_id
+) values (?, ?, ?, ?)
+ON CONFLICT (deck, webhook, day) DO UPDATE SET
+  thread_id = excluded.thread_id
+`
+
+type PutThreadParams struct {
+	Deck     string `json:"deck"`
+	Webhook  string `json:"webhook"`
+	Day      string `json:"day"`
+	ThreadID string `json:"thread_id"`
+}
+
+func (q *Queries) PutThread(ctx context.Context, arg PutThreadParams) error {
+	_, err := q.db.ExecContext(ctx, putThread,
+		arg.Deck,
+		arg.Webhook,
+		arg.Day,
+		arg.ThreadID,
+	)
+	return err
+}
+
+const releaseLease = `-- name: ReleaseLease :exec
+DELETE FROM leases
+WHERE name = ? AND holder = ?
+`
+
+type ReleaseLeaseParams struct {
+	Name   string `json:"name"`
+	Holder string `json:"holder"`
+}
+
+func (q *Queries) ReleaseLease(ctx context.Context, arg ReleaseLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseLease, arg.Name, arg.Holder)
+	return err
+}
+
+const removeDeckRole = `-- name: RemoveDeckRole :execrows
+DELETE FROM deck_roles
+WHERE deck = ? AND role = ?
+`
+
+type RemoveDeckRoleParams struct {
+	Deck string `json:"deck"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) RemoveDeckRole(ctx context.Context, arg RemoveDeckRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeDeckRole, arg.Deck, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
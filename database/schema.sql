@@ -5,8 +5,17 @@ CREATE TABLE IF NOT EXISTS flashcards (
   class_context TEXT NOT NULL,
   ai_overview TEXT,
   thumbnail TEXT,
+  textbook TEXT NOT NULL DEFAULT '',
+  subject TEXT NOT NULL DEFAULT '',
+  chapter INTEGER NOT NULL DEFAULT 0,
+  chapter_title TEXT NOT NULL DEFAULT '',
+  language TEXT NOT NULL DEFAULT '',
+  kind TEXT NOT NULL DEFAULT '',
+  reveal TEXT,
+  direction TEXT NOT NULL DEFAULT '',
+  style TEXT,
 
-  PRIMARY KEY (header, origin, class_context)
+  PRIMARY KEY (header, origin, textbook, chapter, class_context, direction)
 );
 
 
@@ -15,5 +24,42 @@ CREATE TABLE IF NOT EXISTS jobs (
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL,
 
   -- 0: Success, 1: This failed, 2: This and previous failed....
-  failures INTEGER NOT NULL
+  failures INTEGER NOT NULL,
+
+  -- Deck the job was run for. Empty for the default deck.
+  deck TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS jobs_deck_created_at ON jobs (deck, created_at);
+
+
+-- Roles mentioned whenever a deck sends a card, on top of its configured
+-- mentions. Deck is empty for the default deck.
+CREATE TABLE IF NOT EXISTS deck_roles (
+  deck TEXT NOT NULL,
+  role TEXT NOT NULL,
+
+  PRIMARY KEY (deck, role)
+);
+
+
+-- Thread each deck's cards for a day were grouped in (see serve --threads).
+CREATE TABLE IF NOT EXISTS threads (
+  deck TEXT NOT NULL,
+  -- SHA-256 of the webhook so the secret is not stored.
+  webhook TEXT NOT NULL,
+  -- YYYY-MM-DD
+  day TEXT NOT NULL,
+  thread_id TEXT NOT NULL,
+
+  PRIMARY KEY (deck, webhook, day)
+);
+
+
+-- Short lived locks so only one process works on something at a time.
+CREATE TABLE IF NOT EXISTS leases (
+  name TEXT PRIMARY KEY,
+  holder TEXT NOT NULL,
+  -- Unix milliseconds
+  expires_at INTEGER NOT NULL
 );
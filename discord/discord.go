@@ -5,18 +5,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
 )
 
 type Embed struct {
 	Content         string          `json:"content,omitzero"`
 	AllowedMentions AllowedMentions `json:"allowed_mentions,omitzero"`
 	Messages        []Message       `json:"embeds,omitzero"`
+	Attachments     []Attachment    `json:"attachments,omitzero"`
+	// Creates a post with this name when the webhook is for a forum channel.
+	ThreadName string `json:"thread_name,omitzero"`
+
+	// Uploaded with the message. Set Attachments with Attach.
+	Files []File `json:"-"`
+	// Existing thread to post in instead of the webhook's channel.
+	ThreadID string `json:"-"`
+}
+
+// Posted is the message Discord created.
+type Posted struct {
+	ID string `json:"id"`
+	// Thread the message is in, if it was posted in one (ex: the forum post
+	// created by ThreadName).
+	ChannelID string `json:"channel_id"`
 }
 
+// File is a local file uploaded with a message.
+type File struct {
+	Name string
+	Path string
+}
+
+type Attachment struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+}
+
+// Attach uploads the file at path with the message and returns the URL to
+// reference it by in the embed (ex: as an Image).
+func (embed *Embed) Attach(name string, path string) string {
+	embed.Attachments = append(embed.Attachments, Attachment{
+		ID:       len(embed.Files),
+		Filename: name,
+	})
+	embed.Files = append(embed.Files, File{Name: name, Path: path})
+	return "attachment://" + name
+}
+
+// AllowedMentions limits who the message's content can ping (see
+// Embed.Mention). Parse lists types pinged wholesale (ex: everyone).
 type AllowedMentions struct {
 	Parse []string `json:"parse"`
-	Users []string `json:"users"`
+	Users []string `json:"users,omitempty"`
+	Roles []string `json:"roles,omitempty"`
 }
 
 type Footer struct {
@@ -24,8 +68,9 @@ type Footer struct {
 }
 
 type Author struct {
-	Name string `json:"name"`
-	Url  string `json:"url,omitzero"`
+	Name    string `json:"name"`
+	Url     string `json:"url,omitzero"`
+	IconURL string `json:"icon_url,omitzero"`
 }
 
 type Message struct {
@@ -51,29 +96,127 @@ type Field struct {
 	TimeISO string `json:"timestamp,omitzero"`
 }
 
+// Post sends embed to webhook. It is checked against Discord's limits (see
+// Validate) first and only pings what AllowedMentions allows.
 func (embed *Embed) Post(webhook string) error {
+	_, err := embed.Send(webhook)
+	return err
+}
+
+// Send is Post, returning the created message (ex: to find the thread
+// ThreadName created).
+func (embed *Embed) Send(webhook string) (Posted, error) {
+	var posted Posted
+	if err := embed.Validate(); err != nil {
+		return posted, err
+	}
+	link, err := url.Parse(webhook)
+	if err != nil {
+		return posted, fmt.Errorf("parsing webhook: %w", err)
+	}
+	query := link.Query()
+	// Respond with the message instead of 204 No Content.
+	query.Set("wait", "true")
+	if embed.ThreadID != "" {
+		query.Set("thread_id", embed.ThreadID)
+	}
+	link.RawQuery = query.Encode()
+
+	// Without allowed_mentions Discord pings anything in the content.
+	if embed.AllowedMentions.Parse == nil {
+		embed.AllowedMentions.Parse = []string{}
+	}
 	data, err := json.Marshal(embed)
 	if err != nil {
-		return err
+		return posted, err
+	}
+
+	body, contentType := io.Reader(bytes.NewBuffer(data)), "application/json"
+	if len(embed.Files) > 0 {
+		var buffer bytes.Buffer
+		if contentType, err = embed.multipart(&buffer, data); err != nil {
+			return posted, err
+		}
+		body = &buffer
 	}
 
-	request, err := http.NewRequest("POST", webhook, bytes.NewBuffer(data))
+	request, err := http.NewRequest("POST", link.String(), body)
 	if err != nil {
-		return err
+		return posted, err
 	}
-	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Type", contentType)
 
 	client := &http.Client{}
 	response, err := client.Do(request)
 	if err != nil {
-		return err
+		return posted, err
 	}
 	defer response.Body.Close()
 
 	content, _ := io.ReadAll(response.Body)
 
 	if response.StatusCode >= 400 {
-		return fmt.Errorf("response failed: got: %d: %s", response.StatusCode, string(content))
+		return posted, &ResponseError{
+			StatusCode: response.StatusCode,
+			Body:       string(content),
+		}
+	}
+	if err := json.Unmarshal(content, &posted); err != nil {
+		return posted, fmt.Errorf("reading response: %w", err)
+	}
+	return posted, nil
+}
+
+// multipart writes the payload and files as a multipart form, returning its
+// content type.
+func (embed *Embed) multipart(w io.Writer, payload []byte) (string, error) {
+	writer := multipart.NewWriter(w)
+	if err := writer.WriteField("payload_json", string(payload)); err != nil {
+		return "", err
+	}
+	for i, file := range embed.Files {
+		part, err := writer.CreateFormFile(fmt.Sprintf("files[%d]", i), file.Name)
+		if err != nil {
+			return "", err
+		}
+		if err := copyFile(part, file.Path); err != nil {
+			return "", fmt.Errorf("attaching %s: %w", file.Name, err)
+		}
+	}
+	return writer.FormDataContentType(), writer.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// ResponseError is returned when Discord rejects a webhook request.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("response failed: got: %d: %s", err.StatusCode, err.Body)
+}
+
+// CodeUnknownChannel is the JSON error code Discord uses for a channel or
+// thread that does not exist.
+const CodeUnknownChannel = 10003
+
+// Code returns the JSON error code in the body, or 0 if there is none.
+func (err *ResponseError) Code() int {
+	var body struct {
+		Code int `json:"code"`
+	}
+	if json.Unmarshal([]byte(err.Body), &body) != nil {
+		return 0
 	}
-	return nil
+	return body.Code
 }
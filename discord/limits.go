@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits Discord puts on webhook messages, in characters. Going over any of
+// them gets the request rejected with a 400.
+const (
+	MaxContent     = 2000
+	MaxEmbeds      = 10
+	MaxTitle       = 256
+	MaxDescription = 4096
+	MaxFields      = 25
+	MaxFieldName   = 256
+	MaxFieldValue  = 1024
+	MaxFooter      = 2048
+	MaxAuthorName  = 256
+	MaxThreadName  = 100
+	// MaxTotal caps the title, description, field names and values, footer
+	// and author name of every embed in a message combined.
+	MaxTotal = 6000
+)
+
+// LimitError is returned when part of a message is too long for Discord.
+type LimitError struct {
+	// Part of the message (ex: embeds[0].fields[2].value).
+	Path   string
+	Length int
+	Limit  int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("%s is %d characters, over the limit of %d", err.Path, err.Length, err.Limit)
+}
+
+// Length counts text the way Discord does for its limits.
+func Length(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Length is how much of MaxTotal message uses.
+func (message Message) Length() int {
+	total := Length(message.Title) + Length(message.Description) + Length(message.Footer.Text) + Length(message.Author.Name)
+	for _, field := range message.Fields {
+		total += Length(field.Name) + Length(field.Value)
+	}
+	return total
+}
+
+// Validate checks embed against Discord's limits so it can be fixed before
+// being posted.
+func (embed *Embed) Validate() error {
+	var errs []error
+	check := func(path string, length int, limit int) {
+		if length > limit {
+			errs = append(errs, &LimitError{Path: path, Length: length, Limit: limit})
+		}
+	}
+
+	check("content", Length(embed.Content), MaxContent)
+	check("thread_name", Length(embed.ThreadName), MaxThreadName)
+	check("embeds", len(embed.Messages), MaxEmbeds)
+	var total int
+	for i, message := range embed.Messages {
+		path := fmt.Sprintf("embeds[%d]", i)
+		check(path+".title", Length(message.Title), MaxTitle)
+		check(path+".description", Length(message.Description), MaxDescription)
+		check(path+".footer.text", Length(message.Footer.Text), MaxFooter)
+		check(path+".author.name", Length(message.Author.Name), MaxAuthorName)
+		check(path+".fields", len(message.Fields), MaxFields)
+		for j, field := range message.Fields {
+			path := fmt.Sprintf("%s.fields[%d]", path, j)
+			check(path+".name", Length(field.Name), MaxFieldName)
+			check(path+".value", Length(field.Value), MaxFieldValue)
+		}
+		total += message.Length()
+	}
+	check("embeds (total)", total, MaxTotal)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package discord
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Types of mentions.
+const (
+	MentionUser     = "user"
+	MentionRole     = "role"
+	MentionEveryone = "everyone"
+	MentionHere     = "here"
+)
+
+// Mention pings a user, a role or everyone.
+type Mention struct {
+	Type string
+	// Snowflake of the user or role.
+	ID string
+}
+
+var mentionSyntax = regexp.MustCompile(`^<@(!|&)?(\d+)>$`)
+var snowflake = regexp.MustCompile(`^\d+$`)
+
+// ParseMention parses a mention as written in Discord (ex: <@123>, <@&456> or
+// @everyone). IDs can also be given as user:123 or role:456.
+func ParseMention(text string) (Mention, error) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "@everyone":
+		return Mention{Type: MentionEveryone}, nil
+	case "@here":
+		return Mention{Type: MentionHere}, nil
+	}
+	if match := mentionSyntax.FindStringSubmatch(text); match != nil {
+		if match[1] == "&" {
+			return Mention{Type: MentionRole, ID: match[2]}, nil
+		}
+		return Mention{Type: MentionUser, ID: match[2]}, nil
+	}
+	if kind, id, ok := strings.Cut(text, ":"); ok && (kind == MentionUser || kind == MentionRole) && snowflake.MatchString(id) {
+		return Mention{Type: kind, ID: id}, nil
+	}
+	return Mention{}, fmt.Errorf("invalid mention %q (expected <@user>, <@&role>, user:ID, role:ID, @everyone or @here)", text)
+}
+
+func ParseMentions(texts []string) ([]Mention, error) {
+	var mentions []Mention
+	for _, text := range texts {
+		mention, err := ParseMention(text)
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
+
+func (mention Mention) String() string {
+	switch mention.Type {
+	case MentionUser:
+		return "<@" + mention.ID + ">"
+	case MentionRole:
+		return "<@&" + mention.ID + ">"
+	default:
+		return "@" + mention.Type
+	}
+}
+
+// Mention pings mentions in the message's content, allowing exactly them.
+// Anything else that looks like a mention is not pinged.
+func (embed *Embed) Mention(mentions ...Mention) {
+	var content []string
+	allowed := AllowedMentions{Parse: []string{}}
+	for _, mention := range mentions {
+		// The same role can come from the shared mentions and a deck's.
+		if slices.Contains(content, mention.String()) {
+			continue
+		}
+		content = append(content, mention.String())
+		switch mention.Type {
+		case MentionUser:
+			allowed.Users = append(allowed.Users, mention.ID)
+		case MentionRole:
+			allowed.Roles = append(allowed.Roles, mention.ID)
+		default:
+			// Discord allows @everyone and @here together.
+			if len(allowed.Parse) == 0 {
+				allowed.Parse = append(allowed.Parse, MentionEveryone)
+			}
+		}
+	}
+	slices.Sort(allowed.Users)
+	slices.Sort(allowed.Roles)
+	allowed.Users = slices.Compact(allowed.Users)
+	allowed.Roles = slices.Compact(allowed.Roles)
+	embed.Content = strings.Join(content, " ")
+	embed.AllowedMentions = allowed
+}
+
+var mentionLike = regexp.MustCompile(`@(everyone|here)|<@[!&]?`)
+
+// Sanitize stops text from mentioning anyone by putting a zero width space
+// after each @.
+func Sanitize(text string) string {
+	return mentionLike.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Replace(match, "@", "@​", 1)
+	})
+}
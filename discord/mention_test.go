@@ -0,0 +1,29 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMention(t *testing.T) {
+	mentions, err := ParseMentions([]string{"<@&2>", "<@1>", "role:2", "<@&3>", "user:1", "@here", "@everyone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var embed Embed
+	embed.Mention(mentions...)
+
+	if want := "<@&2> <@1> <@&3> @here @everyone"; embed.Content != want {
+		t.Errorf("got content %q, want %q", embed.Content, want)
+	}
+	allowed := embed.AllowedMentions
+	if want := []string{"2", "3"}; !slices.Equal(allowed.Roles, want) {
+		t.Errorf("got roles %q, want %q", allowed.Roles, want)
+	}
+	if want := []string{"1"}; !slices.Equal(allowed.Users, want) {
+		t.Errorf("got users %q, want %q", allowed.Users, want)
+	}
+	if want := []string{MentionEveryone}; !slices.Equal(allowed.Parse, want) {
+		t.Errorf("got parse %q, want %q", allowed.Parse, want)
+	}
+}
@@ -0,0 +1,604 @@
+package export
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohhfishal/fishy/flashcard"
+	"github.com/ohhfishal/fishy/version"
+)
+
+// Names of the fields of the exported note type, in order.
+var AnkiFields = []string{"Header", "Description", "Source", "Textbook", "AI Summary", "Thumbnail"}
+
+const (
+	AnkiModelName = "fishy"
+	// Separates fields in notes.flds.
+	ankiFieldSeparator = "\x1f"
+	ankiQuestion       = "{{Header}}"
+	ankiAnswer         = "{{FrontSide}}\n\n<hr id=answer>\n\n{{Description}}" +
+		"{{#Thumbnail}}<br>{{Thumbnail}}{{/Thumbnail}}" +
+		"{{#AI Summary}}<br>{{AI Summary}}{{/AI Summary}}" +
+		"<br><small>{{Source}} {{Textbook}}</small>"
+	ankiCSS = ".card { font-family: arial; font-size: 20px; text-align: center; }\nimg { max-width: 100%; }"
+)
+
+type AnkiCMD struct {
+	Input     Input  `embed:""`
+	Output    string `short:"o" default:"out.apkg" type:"path" help:"File to write to."`
+	Deck      string `default:"fishy" help:"Name of the Anki deck to create."`
+	NoMedia   bool   `help:"Don't download images into the package."`
+	UserAgent string `help:"User-Agent used when downloading images."`
+}
+
+func (config *AnkiCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	cards, err := config.Input.Cards(ctx)
+	if err != nil {
+		return err
+	}
+
+	media := Media{}
+	if !config.NoMedia {
+		media = DownloadMedia(ctx, config.userAgent(), cards, logger)
+	}
+
+	file, err := os.OpenFile(config.Output, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := WriteAnki(ctx, file, config.Deck, cards, media); err != nil {
+		return fmt.Errorf("writing anki package: %w", err)
+	}
+	logger.Info("exported", "cards", len(cards), "media", len(media), "output", config.Output)
+	return file.Close()
+}
+
+func (config *AnkiCMD) userAgent() string {
+	if config.UserAgent != "" {
+		return config.UserAgent
+	}
+	return fmt.Sprintf(flashcard.FUserAgent, version.Repo)
+}
+
+// Media maps image locations (see flashcard.Image.Location) to their
+// contents.
+type Media map[string][]byte
+
+// DownloadMedia fetches the images of every card. Failures are logged and the
+// image is left out.
+func DownloadMedia(ctx context.Context, userAgent string, cards []flashcard.Flashcard, logger *slog.Logger) Media {
+	media := Media{}
+	for _, card := range cards {
+		for _, image := range ankiImages(card) {
+			location := image.Location()
+			if _, ok := media[location]; ok || location == "" {
+				continue
+			}
+			// Mirrored and local images don't need downloading.
+			data, err := os.ReadFile(image.Path)
+			if image.Path == "" || (err != nil && image.Source != "") {
+				data, err = download(ctx, userAgent, image.Source)
+			}
+			if err != nil {
+				logger.Warn("skipping image", "location", location, "err", err)
+				continue
+			}
+			media[location] = data
+		}
+	}
+	return media
+}
+
+// ankiImages returns the images of card included in the package.
+func ankiImages(card flashcard.Flashcard) []flashcard.Image {
+	return []flashcard.Image{card.Thumbnail, card.Reveal}
+}
+
+func download(ctx context.Context, userAgent string, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	request.Header.Set("User-Agent", userAgent)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got status: %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// MediaName is the file name an image is stored under in the package.
+func MediaName(location string) string {
+	sum := sha1.Sum([]byte(location))
+	name := hex.EncodeToString(sum[:8])
+	if parsed, err := url.Parse(location); err == nil {
+		name += path.Ext(parsed.Path)
+	}
+	return name
+}
+
+// WriteAnki writes cards as an Anki package: a zip holding a SQLite
+// collection (schema 11), a media index and the media files.
+func WriteAnki(ctx context.Context, writer io.Writer, deck string, cards []flashcard.Flashcard, media Media) error {
+	dir, err := os.MkdirTemp("", "fishy-anki-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(ctx, collection, deck, cards, media); err != nil {
+		return fmt.Errorf("writing collection: %w", err)
+	}
+
+	archive := zip.NewWriter(writer)
+	if err := addFile(archive, "collection.anki2", collection); err != nil {
+		return err
+	}
+
+	// Media files are stored as "0", "1", ... and named by the "media" index.
+	index := map[string]string{}
+	i := 0
+	for _, card := range cards {
+		for _, image := range ankiImages(card) {
+			data, ok := media[image.Location()]
+			name := MediaName(image.Location())
+			if !ok || containsValue(index, name) {
+				continue
+			}
+			entry, err := archive.Create(strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+			if _, err := entry.Write(data); err != nil {
+				return err
+			}
+			index[strconv.Itoa(i)] = name
+			i++
+		}
+	}
+
+	entry, err := archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(index); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func addFile(archive *zip.Writer, name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+func containsValue(index map[string]string, value string) bool {
+	for _, v := range index {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func writeCollection(ctx context.Context, path string, deck string, cards []flashcard.Flashcard, media Media) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, ankiSchema); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+
+	now := time.Now()
+	modelID := stableID("model:" + AnkiModelName)
+	deckID := stableID("deck:" + deck)
+	col, err := ankiCol(now, modelID, deckID, deck, len(cards))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		col.Created, now.UnixMilli(), now.UnixMilli(), col.Conf, col.Models, col.Decks, col.DeckConf,
+	); err != nil {
+		return fmt.Errorf("inserting collection: %w", err)
+	}
+
+	for i, card := range cards {
+		fields := AnkiNoteFields(card, media)
+		id := now.UnixMilli() + int64(i)
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			id, ankiGUID(card), modelID, now.Unix(), ankiTags(card),
+			strings.Join(fields, ankiFieldSeparator), flashcard.StripHTML(fields[0]), checksum(fields[0]),
+		); err != nil {
+			return fmt.Errorf("inserting note: %w", err)
+		}
+		// New card: type 0, queue 0, due is its position in the new queue.
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`,
+			id, id, deckID, now.Unix(), i+1,
+		); err != nil {
+			return fmt.Errorf("inserting card: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// AnkiNoteFields returns the values of AnkiFields for card as HTML.
+func AnkiNoteFields(card flashcard.Flashcard, media Media) []string {
+	source := html.EscapeString(card.Origin)
+	if text, link, ok := flashcard.ParseLink(card.Origin); ok && webLink(link) {
+		source = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), html.EscapeString(text))
+	}
+
+	var summary string
+	if len(card.AIOverview) > 0 {
+		var builder strings.Builder
+		builder.WriteString("<ul>")
+		for _, line := range card.AIOverview {
+			builder.WriteString("<li>" + html.EscapeString(line) + "</li>")
+		}
+		builder.WriteString("</ul>")
+		summary = builder.String()
+	}
+
+	img := func(image flashcard.Image) string {
+		if _, ok := media[image.Location()]; !ok {
+			return ""
+		}
+		return fmt.Sprintf(`<img src="%s">`, MediaName(image.Location()))
+	}
+
+	// The note type always asks for the first field.
+	question, answer := html.EscapeString(card.Header), html.EscapeString(card.Description)
+	if card.Reversed() {
+		question, answer = answer, question
+	}
+	thumbnail := img(card.Thumbnail)
+	// Occlusions ask about the masked image and answer with the revealed one.
+	if card.Kind == flashcard.KindOcclusion && thumbnail != "" {
+		question += "<br>" + thumbnail
+		thumbnail = cmp.Or(img(card.Reveal), thumbnail)
+	}
+	return []string{
+		question,
+		answer,
+		source,
+		html.EscapeString(card.Context()),
+		summary,
+		thumbnail,
+	}
+}
+
+// webLink reports if link is safe to put in an href (ex: not javascript:).
+func webLink(link string) bool {
+	parsed, err := url.Parse(link)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https")
+}
+
+func ankiTags(card flashcard.Flashcard) string {
+	var tags []string
+	for _, tag := range []string{card.Subject, card.Textbook, card.ClassContext} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if card.Chapter > 0 {
+		tags = append(tags, fmt.Sprintf(flashcard.FClassContext, card.Chapter))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	// Tags are space separated.
+	for i, tag := range tags {
+		tags[i] = strings.ReplaceAll(tag, " ", "_")
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// ankiGUID identifies a note across exports so re-importing updates it.
+func ankiGUID(card flashcard.Flashcard) string {
+	// Context matches the ClassContext cards had before their chapter was its
+	// own field, so older exports still line up.
+	parts := []string{card.Header, card.Origin, card.Context()}
+	if card.Direction != "" {
+		parts = append(parts, card.Direction)
+	}
+	sum := sha1.Sum([]byte(strings.Join(parts, ankiFieldSeparator)))
+	return base64.RawStdEncoding.EncodeToString(sum[:8])
+}
+
+// checksum is the first 8 hex digits of the sha1 of the sort field.
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(flashcard.StripHTML(field)))
+	value, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return value
+}
+
+// stableID returns an id in the range Anki uses (millisecond timestamps) that
+// does not change between exports.
+func stableID(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return 1_000_000_000_000 + int64(hash.Sum64()%1_000_000_000_000)
+}
+
+type ankiCollection struct {
+	Created  int64
+	Conf     string
+	Models   string
+	Decks    string
+	DeckConf string
+}
+
+func ankiCol(now time.Time, modelID int64, deckID int64, deck string, cards int) (ankiCollection, error) {
+	id := strconv.FormatInt(modelID, 10)
+	conf := map[string]any{
+		"nextPos":       cards + 1,
+		"estTimes":      true,
+		"activeDecks":   []int64{deckID},
+		"sortType":      "noteFld",
+		"timeLim":       0,
+		"sortBackwards": false,
+		"addToCur":      true,
+		"curDeck":       deckID,
+		"newBury":       true,
+		"newSpread":     0,
+		"dueCounts":     true,
+		"curModel":      id,
+		"collapseTime":  1200,
+	}
+
+	var fields []map[string]any
+	for i, name := range AnkiFields {
+		fields = append(fields, map[string]any{
+			"name":   name,
+			"ord":    i,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   20,
+			"media":  []string{},
+		})
+	}
+	models := map[string]any{
+		id: map[string]any{
+			"id":       modelID,
+			"name":     AnkiModelName,
+			"type":     0,
+			"mod":      now.Unix(),
+			"usn":      -1,
+			"sortf":    0,
+			"did":      deckID,
+			"flds":     fields,
+			"css":      ankiCSS,
+			"req":      []any{[]any{0, "any", []int{0}}},
+			"tags":     []string{},
+			"vers":     []any{},
+			"latexsvg": false,
+			"latexPre": "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n" +
+				"\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"tmpls": []map[string]any{
+				{
+					"name":  "Card 1",
+					"ord":   0,
+					"qfmt":  ankiQuestion,
+					"afmt":  ankiAnswer,
+					"did":   nil,
+					"bqfmt": "",
+					"bafmt": "",
+				},
+			},
+		},
+	}
+
+	decks := map[string]any{
+		"1":                           ankiDeck(1, "Default", now),
+		strconv.FormatInt(deckID, 10): ankiDeck(deckID, deck, now),
+	}
+
+	deckConf := map[string]any{
+		"1": map[string]any{
+			"id":       1,
+			"name":     "Default",
+			"mod":      0,
+			"usn":      0,
+			"maxTaken": 60,
+			"autoplay": true,
+			"timer":    0,
+			"replayq":  true,
+			"dyn":      false,
+			"new": map[string]any{
+				"delays":        []float64{1, 10},
+				"ints":          []int{1, 4, 7},
+				"initialFactor": 2500,
+				"order":         1,
+				"perDay":        20,
+				"bury":          true,
+			},
+			"rev": map[string]any{
+				"perDay":     200,
+				"ease4":      1.3,
+				"fuzz":       0.05,
+				"ivlFct":     1,
+				"maxIvl":     36500,
+				"bury":       true,
+				"hardFactor": 1.2,
+			},
+			"lapse": map[string]any{
+				"delays":      []float64{10},
+				"mult":        0,
+				"minInt":      1,
+				"leechFails":  8,
+				"leechAction": 0,
+			},
+		},
+	}
+
+	var col ankiCollection
+	for _, value := range []struct {
+		dst *string
+		src any
+	}{
+		{&col.Conf, conf},
+		{&col.Models, models},
+		{&col.Decks, decks},
+		{&col.DeckConf, deckConf},
+	} {
+		data, err := json.Marshal(value.src)
+		if err != nil {
+			return col, err
+		}
+		*value.dst = string(data)
+	}
+
+	year, month, day := now.Date()
+	col.Created = time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Unix()
+	return col, nil
+}
+
+func ankiDeck(id int64, name string, now time.Time) map[string]any {
+	return map[string]any{
+		"id":        id,
+		"name":      name,
+		"desc":      "",
+		"mod":       now.Unix(),
+		"usn":       -1,
+		"collapsed": false,
+		"newToday":  []int{0, 0},
+		"revToday":  []int{0, 0},
+		"lrnToday":  []int{0, 0},
+		"timeToday": []int{0, 0},
+		"dyn":       0,
+		"conf":      1,
+		"extendNew": 10,
+		"extendRev": 50,
+	}
+}
+
+const ankiSchema = `
+CREATE TABLE col (
+  id integer PRIMARY KEY,
+  crt integer NOT NULL,
+  mod integer NOT NULL,
+  scm integer NOT NULL,
+  ver integer NOT NULL,
+  dty integer NOT NULL,
+  usn integer NOT NULL,
+  ls integer NOT NULL,
+  conf text NOT NULL,
+  models text NOT NULL,
+  decks text NOT NULL,
+  dconf text NOT NULL,
+  tags text NOT NULL
+);
+
+CREATE TABLE notes (
+  id integer PRIMARY KEY,
+  guid text NOT NULL,
+  mid integer NOT NULL,
+  mod integer NOT NULL,
+  usn integer NOT NULL,
+  tags text NOT NULL,
+  flds text NOT NULL,
+  sfld integer NOT NULL,
+  csum integer NOT NULL,
+  flags integer NOT NULL,
+  data text NOT NULL
+);
+
+CREATE TABLE cards (
+  id integer PRIMARY KEY,
+  nid integer NOT NULL,
+  did integer NOT NULL,
+  ord integer NOT NULL,
+  mod integer NOT NULL,
+  usn integer NOT NULL,
+  type integer NOT NULL,
+  queue integer NOT NULL,
+  due integer NOT NULL,
+  ivl integer NOT NULL,
+  factor integer NOT NULL,
+  reps integer NOT NULL,
+  lapses integer NOT NULL,
+  left integer NOT NULL,
+  odue integer NOT NULL,
+  odid integer NOT NULL,
+  flags integer NOT NULL,
+  data text NOT NULL
+);
+
+CREATE TABLE revlog (
+  id integer PRIMARY KEY,
+  cid integer NOT NULL,
+  usn integer NOT NULL,
+  ease integer NOT NULL,
+  ivl integer NOT NULL,
+  lastIvl integer NOT NULL,
+  factor integer NOT NULL,
+  time integer NOT NULL,
+  type integer NOT NULL
+);
+
+CREATE TABLE graves (
+  usn integer NOT NULL,
+  oid integer NOT NULL,
+  type integer NOT NULL
+);
+
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
@@ -0,0 +1,225 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ohhfishal/fishy/flashcard"
+	_ "modernc.org/sqlite"
+)
+
+var discard = slog.New(slog.DiscardHandler)
+
+// TestWriteAnki writes a package and opens it again like Anki would.
+func TestWriteAnki(t *testing.T) {
+	dir := t.TempDir()
+	masked, revealed := filepath.Join(dir, "masked.png"), filepath.Join(dir, "revealed.png")
+	for path, data := range map[string]string{masked: "masked", revealed: "revealed"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	photosynthesis := flashcard.Flashcard{
+		Header:      "Photosynthesis",
+		Description: "Plants <b>convert</b> light into energy.",
+		Origin:      "[wikipedia](https://en.wikipedia.org/wiki/Photosynthesis)",
+		Textbook:    "Campbell Biology",
+		Subject:     "Biology",
+		Chapter:     10,
+		AIOverview:  []string{"Happens in chloroplasts."},
+		Thumbnail:   flashcard.Image{Source: "https://upload.wikimedia.org/leaf.png"},
+	}
+	reverse := photosynthesis
+	reverse.Direction = flashcard.DirectionReverse
+	respiration := flashcard.Flashcard{
+		Header:      "Cellular respiration",
+		Description: "Cells release energy from glucose.",
+		Origin:      "Campbell Biology",
+		Thumbnail:   flashcard.Image{Source: "https://upload.wikimedia.org/missing.png"},
+	}
+	occlusion := flashcard.Flashcard{
+		Header:      "Heart",
+		Description: "Left ventricle",
+		Origin:      "heart.png#Left ventricle",
+		Kind:        flashcard.KindOcclusion,
+		Thumbnail:   flashcard.Image{Path: masked},
+		Reveal:      flashcard.Image{Path: revealed},
+	}
+	cards := []flashcard.Flashcard{photosynthesis, reverse, respiration, occlusion}
+
+	media := DownloadMedia(context.Background(), "test", []flashcard.Flashcard{occlusion}, discard)
+	media["https://upload.wikimedia.org/leaf.png"] = []byte("leaf")
+
+	var buffer bytes.Buffer
+	if err := WriteAnki(context.Background(), &buffer, "Biology", cards, media); err != nil {
+		t.Fatal(err)
+	}
+	files := unzip(t, buffer.Bytes())
+
+	t.Run("notes", func(t *testing.T) {
+		db := openCollection(t, files["collection.anki2"])
+		rows, err := db.Query(`SELECT guid, flds, tags FROM notes ORDER BY id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var guids []string
+		var i int
+		for ; rows.Next(); i++ {
+			var guid, fields, tags string
+			if err := rows.Scan(&guid, &fields, &tags); err != nil {
+				t.Fatal(err)
+			}
+			guids = append(guids, guid)
+			want := AnkiNoteFields(cards[i], media)
+			if got := strings.Split(fields, ankiFieldSeparator); !slices.Equal(got, want) {
+				t.Errorf("note %d: got fields %q, want %q", i, got, want)
+			}
+			if i < 2 && tags != " Biology Campbell_Biology Chapter:_10 " {
+				t.Errorf("note %d: got tags %q", i, tags)
+			}
+		}
+		if i != len(cards) {
+			t.Fatalf("got %d notes, want %d", i, len(cards))
+		}
+		if unique := slices.Compact(slices.Sorted(slices.Values(guids))); len(unique) != len(cards) {
+			t.Errorf("notes share guids: %q", guids)
+		}
+
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM cards JOIN notes ON cards.nid = notes.id`).Scan(&count); err != nil {
+			t.Fatal(err)
+		} else if count != len(cards) {
+			t.Errorf("got %d cards, want %d", count, len(cards))
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		fields := AnkiNoteFields(photosynthesis, media)
+		if fields[0] != "Photosynthesis" || fields[1] != "Plants &lt;b&gt;convert&lt;/b&gt; light into energy." {
+			t.Errorf("got question and answer %q, %q", fields[0], fields[1])
+		}
+		if want := `<a href="https://en.wikipedia.org/wiki/Photosynthesis">wikipedia</a>`; fields[2] != want {
+			t.Errorf("got source %q, want %q", fields[2], want)
+		}
+		if want := `<ul><li>Happens in chloroplasts.</li></ul>`; fields[4] != want {
+			t.Errorf("got summary %q, want %q", fields[4], want)
+		}
+		if want := `<img src="` + MediaName("https://upload.wikimedia.org/leaf.png") + `">`; fields[5] != want {
+			t.Errorf("got thumbnail %q, want %q", fields[5], want)
+		}
+
+		reverse := AnkiNoteFields(reverse, media)
+		if reverse[0] != fields[1] || reverse[1] != fields[0] {
+			t.Errorf("reverse card does not swap question and answer: %q", reverse[:2])
+		}
+
+		// Thumbnails that failed to download are left out.
+		if fields := AnkiNoteFields(respiration, media); fields[2] != "Campbell Biology" || fields[5] != "" {
+			t.Errorf("got source and thumbnail %q, %q", fields[2], fields[5])
+		}
+
+		occlusion := AnkiNoteFields(occlusion, media)
+		if want := `Heart<br><img src="` + MediaName(masked) + `">`; occlusion[0] != want {
+			t.Errorf("got question %q, want %q", occlusion[0], want)
+		}
+		if want := `<img src="` + MediaName(revealed) + `">`; occlusion[5] != want {
+			t.Errorf("got thumbnail %q, want %q", occlusion[5], want)
+		}
+	})
+
+	t.Run("models", func(t *testing.T) {
+		db := openCollection(t, files["collection.anki2"])
+		var models string
+		if err := db.QueryRow(`SELECT models FROM col`).Scan(&models); err != nil {
+			t.Fatal(err)
+		}
+		var parsed map[string]struct {
+			Name   string `json:"name"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"flds"`
+		}
+		if err := json.Unmarshal([]byte(models), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		for _, model := range parsed {
+			var names []string
+			for _, field := range model.Fields {
+				names = append(names, field.Name)
+			}
+			if model.Name != AnkiModelName || !slices.Equal(names, AnkiFields) {
+				t.Errorf("got model %s with fields %q", model.Name, names)
+			}
+		}
+	})
+
+	t.Run("media", func(t *testing.T) {
+		var index map[string]string
+		if err := json.Unmarshal(files["media"], &index); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			MediaName("https://upload.wikimedia.org/leaf.png"): "leaf",
+			MediaName(masked):   "masked",
+			MediaName(revealed): "revealed",
+		}
+		if len(index) != len(want) {
+			t.Errorf("got %d media files, want %d: %v", len(index), len(want), index)
+		}
+		for number, name := range index {
+			if data, ok := want[name]; !ok {
+				t.Errorf("unexpected media %s", name)
+			} else if string(files[number]) != data {
+				t.Errorf("media %s (%s): got %q, want %q", number, name, files[number], data)
+			}
+		}
+	})
+}
+
+func unzip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func openCollection(t *testing.T, data []byte) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
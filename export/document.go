@@ -0,0 +1,158 @@
+package export
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+//go:embed document.html.tmpl
+var htmlTemplate string
+
+var documentTemplate = template.Must(template.New("document").Funcs(template.FuncMap{
+	// Links are written out by the "link" template so html/template
+	// sanitizes their URL (ex: javascript: links).
+	"parseLink": func(origin string) Link {
+		if text, url, ok := flashcard.ParseLink(origin); ok {
+			return Link{Text: text, URL: url}
+		}
+		return Link{Text: origin}
+	},
+}).Parse(htmlTemplate))
+
+// Link is an Origin in a document. URL is empty if it was not a link.
+type Link struct {
+	Text string
+	URL  string
+}
+
+// Section is a group of cards printed under one heading.
+type Section struct {
+	Title string
+	Cards []flashcard.Flashcard
+}
+
+// Sections groups cards by textbook and chapter (see Flashcard.Context),
+// ordering chapters numerically.
+func Sections(cards []flashcard.Flashcard) []Section {
+	var sections []Section
+	for _, card := range cards {
+		title := card.Context()
+		i := slices.IndexFunc(sections, func(section Section) bool {
+			return section.Title == title
+		})
+		if i < 0 {
+			sections = append(sections, Section{Title: title})
+			i = len(sections) - 1
+		}
+		sections[i].Cards = append(sections[i].Cards, card)
+	}
+	// Every card in a section shares the same context.
+	slices.SortStableFunc(sections, func(a, b Section) int {
+		x, y := a.Cards[0], b.Cards[0]
+		return cmp.Or(
+			strings.Compare(x.Subject, y.Subject),
+			strings.Compare(x.Textbook, y.Textbook),
+			cmp.Compare(x.Chapter, y.Chapter),
+			strings.Compare(x.ClassContext, y.ClassContext),
+		)
+	})
+	return sections
+}
+
+type MarkdownCMD struct {
+	Input  Input  `embed:""`
+	Output string `short:"o" default:"out.md" type:"path" help:"File to write to."`
+	Title  string `default:"Flashcards" help:"Title of the document."`
+}
+
+func (config *MarkdownCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	cards, err := config.Input.Cards(ctx)
+	if err != nil {
+		return err
+	}
+	return writeFile(config.Output, func(w io.Writer) error {
+		return WriteMarkdown(w, config.Title, Sections(cards))
+	})
+}
+
+type HTMLCMD struct {
+	Input  Input  `embed:""`
+	Output string `short:"o" default:"out.html" type:"path" help:"File to write to."`
+	Title  string `default:"Flashcards" help:"Title of the document."`
+	Layout string `enum:"list,cards" default:"list" help:"Layout to print (${enum}). cards prints two columns (front and back) to cut out."`
+}
+
+func (config *HTMLCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	cards, err := config.Input.Cards(ctx)
+	if err != nil {
+		return err
+	}
+	return writeFile(config.Output, func(w io.Writer) error {
+		return WriteHTML(w, config.Title, config.Layout, Sections(cards))
+	})
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("opening output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		return fmt.Errorf("writing to output: %w", err)
+	}
+	return file.Close()
+}
+
+func WriteMarkdown(writer io.Writer, title string, sections []Section) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n", title)
+	for _, section := range sections {
+		if section.Title != "" {
+			fmt.Fprintf(&builder, "\n## %s\n", section.Title)
+		}
+		for _, card := range section.Cards {
+			fmt.Fprintf(&builder, "\n### %s\n\n", card.Header)
+			if location := card.Thumbnail.Location(); location != "" {
+				fmt.Fprintf(&builder, "![%s](%s)\n\n", card.Header, location)
+			}
+			// The revealed diagram of occlusion cards answers the masked one.
+			if location := card.Reveal.Location(); location != "" {
+				fmt.Fprintf(&builder, "![%s](%s)\n\n", card.Description, location)
+			}
+			if card.Description != "" {
+				fmt.Fprintf(&builder, "%s\n\n", card.Description)
+			}
+			for _, line := range card.AIOverview {
+				fmt.Fprintf(&builder, "- %s\n", line)
+			}
+			if len(card.AIOverview) > 0 {
+				builder.WriteString("\n")
+			}
+			if card.Origin != "" {
+				fmt.Fprintf(&builder, "Source: %s\n", card.Origin)
+			}
+		}
+	}
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func WriteHTML(writer io.Writer, title string, layout string, sections []Section) error {
+	return documentTemplate.Execute(writer, map[string]any{
+		"Title":    title,
+		"Layout":   layout,
+		"Sections": sections,
+	})
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+var occlusionCard = flashcard.Flashcard{
+	Header:      "Heart",
+	Description: "Left ventricle",
+	Origin:      "heart.png#Left ventricle",
+	Kind:        flashcard.KindOcclusion,
+	Thumbnail:   flashcard.Image{Path: "images/masked.png"},
+	Reveal:      flashcard.Image{Path: "images/revealed.png"},
+}
+
+var cellsPattern = regexp.MustCompile(`(?s)<td class="front">(.*?)</td>\s*<td class="back">(.*?)</td>`)
+
+func TestWriteHTMLOcclusion(t *testing.T) {
+	sections := Sections([]flashcard.Flashcard{occlusionCard})
+
+	t.Run("cards", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := WriteHTML(&buffer, "Anatomy", "cards", sections); err != nil {
+			t.Fatal(err)
+		}
+		cells := cellsPattern.FindStringSubmatch(buffer.String())
+		if cells == nil {
+			t.Fatalf("no front and back in:\n%s", buffer.String())
+		}
+		front, back := cells[1], cells[2]
+		if !strings.Contains(front, `src="images/masked.png"`) || strings.Contains(front, "revealed.png") {
+			t.Errorf("front should only show the masked image: %s", front)
+		}
+		if !strings.Contains(back, `src="images/revealed.png"`) || strings.Contains(back, "masked.png") {
+			t.Errorf("back should only show the revealed image: %s", back)
+		}
+		if strings.Contains(front, "Left ventricle") || !strings.Contains(back, "Left ventricle") {
+			t.Errorf("label should only be on the back: front %s, back %s", front, back)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var buffer bytes.Buffer
+		if err := WriteHTML(&buffer, "Anatomy", "list", sections); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`src="images/masked.png"`, `src="images/revealed.png" alt="Left ventricle"`} {
+			if !strings.Contains(buffer.String(), want) {
+				t.Errorf("missing %s in:\n%s", want, buffer.String())
+			}
+		}
+	})
+}
+
+func TestWriteMarkdownOcclusion(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteMarkdown(&buffer, "Anatomy", Sections([]flashcard.Flashcard{occlusionCard})); err != nil {
+		t.Fatal(err)
+	}
+	want := "### Heart\n\n![Heart](images/masked.png)\n\n![Left ventricle](images/revealed.png)\n\nLeft ventricle\n\n"
+	if !strings.Contains(buffer.String(), want) {
+		t.Errorf("got:\n%s\nwant it to contain:\n%s", buffer.String(), want)
+	}
+}
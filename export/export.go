@@ -0,0 +1,47 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+type CMD struct {
+	Anki     AnkiCMD     `cmd:"" help:"Export cards as an Anki package (.apkg)."`
+	Markdown MarkdownCMD `cmd:"" help:"Export cards as a Markdown document."`
+	HTML     HTMLCMD     `cmd:"" name:"html" help:"Export cards as a printable HTML document."`
+}
+
+// Input selects where (and which) cards are exported from.
+type Input struct {
+	File     string           `short:"f" default:"out.json" type:"path" help:"Generated flashcard file to read."`
+	Database string           `help:"SQLite database to read cards from instead of --file."`
+	Filter   flashcard.Filter `embed:"" group:"Filter"`
+}
+
+func (input Input) Cards(ctx context.Context) ([]flashcard.Flashcard, error) {
+	if err := input.Filter.Validate(); err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+	if input.Database == "" {
+		cards, err := flashcard.ReadFlashcards(input.File)
+		if err != nil {
+			return nil, err
+		}
+		return input.Filter.Apply(cards), nil
+	}
+
+	db, err := database.Connect(ctx, "sqlite", input.Database)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	cards, err := db.Flashcards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting cards: %w", err)
+	}
+	return input.Filter.Apply(cards), nil
+}
@@ -0,0 +1,54 @@
+package flashcard
+
+// Directions to quiz a term in (see Term.Direction). Forward cards leave
+// Flashcard.Direction empty.
+const (
+	// Show the term and ask for its description.
+	DirectionForward = "forward"
+	// Show the description and ask for the term.
+	DirectionReverse = "reverse"
+	// Make both a forward and a reverse card.
+	DirectionBoth = "both"
+)
+
+// Directions returns every direction a term can be quizzed in.
+func Directions() []string {
+	return []string{DirectionForward, DirectionReverse, DirectionBoth}
+}
+
+// Reversed reports whether the card shows the description and asks for the
+// header.
+func (card Flashcard) Reversed() bool {
+	return card.Direction == DirectionReverse
+}
+
+// Reversible reports whether the card makes sense the other way around.
+// Occlusion cards already hide their answer in the image.
+func (card Flashcard) Reversible() bool {
+	return card.Kind == KindStandard && card.Description != "" && !card.Reversed()
+}
+
+// Reverse turns a forward card into one asking for its header. See Redact to
+// keep the description from giving the answer away.
+func (card Flashcard) Reverse() Flashcard {
+	card.Direction = DirectionReverse
+	return card
+}
+
+// ApplyDirections makes the cards quizzed in directions[i] out of cards[i].
+// Cards that are not reversible are kept as they are.
+func ApplyDirections(cards []Flashcard, directions []string) []Flashcard {
+	var result []Flashcard
+	for i, card := range cards {
+		direction := directions[i]
+		if !card.Reversible() || direction == "" || direction == DirectionForward {
+			result = append(result, card)
+			continue
+		}
+		if direction == DirectionBoth {
+			result = append(result, card)
+		}
+		result = append(result, card.Reverse())
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package flashcard
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Filter selects a subset of cards. Empty fields match every card. Patterns
+// are globs (see path.Match).
+type Filter struct {
+	Textbooks []string `json:"textbooks,omitempty" yaml:"textbooks" name:"textbook" help:"Only cards from textbooks matching these patterns."`
+	Subjects  []string `json:"subjects,omitempty" yaml:"subjects" name:"subject" help:"Only cards from subjects matching these patterns."`
+	Chapters  []int    `json:"chapters,omitempty" yaml:"chapters" name:"chapter" help:"Only cards from these chapters."`
+	// Matched against ClassContext.
+	Contexts []string `json:"contexts,omitempty" yaml:"contexts" name:"context" help:"Only cards with a context (ex: imported tags) matching these patterns."`
+}
+
+func (filter Filter) Match(card Flashcard) bool {
+	return matchAny(filter.Textbooks, card.Textbook) &&
+		matchAny(filter.Subjects, card.Subject) &&
+		matchAny(filter.Contexts, card.ClassContext) &&
+		(len(filter.Chapters) == 0 || slices.Contains(filter.Chapters, card.Chapter))
+}
+
+// Empty reports whether the filter matches every card.
+func (filter Filter) Empty() bool {
+	return len(filter.Textbooks) == 0 && len(filter.Subjects) == 0 &&
+		len(filter.Chapters) == 0 && len(filter.Contexts) == 0
+}
+
+// Validate reports malformed patterns.
+func (filter Filter) Validate() error {
+	for _, pattern := range slices.Concat(filter.Textbooks, filter.Subjects, filter.Contexts) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (filter Filter) Apply(cards []Flashcard) []Flashcard {
+	var matched []Flashcard
+	for _, card := range cards {
+		if filter.Match(card) {
+			matched = append(matched, card)
+		}
+	}
+	return matched
+}
+
+// matchAny reports if value matches any of patterns, or patterns is empty.
+func matchAny(patterns []string, value string) bool {
+	return len(patterns) == 0 || slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := path.Match(pattern, value)
+		return ok
+	})
+}
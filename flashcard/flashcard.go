@@ -2,57 +2,163 @@ package flashcard
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/goccy/go-yaml"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
+// FClassContext formats a card's chapter. Cards generated before Chapter
+// existed stored this in ClassContext.
+const FClassContext = "Chapter: %d"
+
 type Flashcard struct {
-	Header       string   `json:"header,omitempty"`
-	Description  string   `json:"description,omitempty"`
-	AIOverview   []string `json:"ai_overview,omitempty"`
-	Origin       string   `json:"origin"`
-	ClassContext string   `json:"class_context"`
-	Thumbnail    Image    `json:"thumbnail"`
+	Header      string   `json:"header,omitempty"`
+	Description string   `json:"description,omitempty"`
+	AIOverview  []string `json:"ai_overview,omitempty"`
+	Origin      string   `json:"origin"`
+	// Where the term came from. All optional.
+	Textbook     string `json:"textbook,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	Chapter      int    `json:"chapter,omitempty"`
+	ChapterTitle string `json:"chapter_title,omitempty"`
+	// Language of the card (ex: fr), or both languages of a bilingual card
+	// (ex: fr/en).
+	Language string `json:"language,omitempty"`
+	// Free form context for cards that don't come from a textbook (ex: the tags
+	// of imported notes).
+	ClassContext string `json:"class_context"`
+	// KindStandard or KindOcclusion.
+	Kind string `json:"kind,omitempty"`
+	// DirectionForward (the default) or DirectionReverse.
+	Direction string `json:"direction,omitempty"`
+	Thumbnail Image  `json:"thumbnail"`
+	// From the card's textbook and subject.
+	Style Style `json:"style,omitzero"`
+	// Shown (spoilered) with the answer (ex: the diagram with the region
+	// outlined).
+	Reveal Image `json:"reveal,omitzero"`
+
+	// Other names of the header (ex: redirects to its article) to redact
+	// along with it. Not saved.
+	aliases []string
+}
+
+// Context describes where the card came from in a single line.
+// (ex: Campbell Biology (Biology) • Chapter: 3 • Cell Structure).
+func (card Flashcard) Context() string {
+	var parts []string
+	if card.Textbook != "" {
+		if card.Subject != "" {
+			parts = append(parts, fmt.Sprintf("%s (%s)", card.Textbook, card.Subject))
+		} else {
+			parts = append(parts, card.Textbook)
+		}
+	} else if card.Subject != "" {
+		parts = append(parts, card.Subject)
+	}
+	if card.Chapter > 0 {
+		parts = append(parts, fmt.Sprintf(FClassContext, card.Chapter))
+	}
+	if card.ChapterTitle != "" {
+		parts = append(parts, card.ChapterTitle)
+	}
+	if card.ClassContext != "" {
+		parts = append(parts, card.ClassContext)
+	}
+	return strings.Join(parts, " • ")
+}
+
+// upgrade moves the chapter out of ClassContext for cards generated before
+// Chapter existed.
+func (card *Flashcard) upgrade() {
+	if card.Chapter != 0 {
+		return
+	}
+	var chapter int
+	if _, err := fmt.Sscanf(card.ClassContext, FClassContext, &chapter); err == nil && card.ClassContext == fmt.Sprintf(FClassContext, chapter) {
+		card.Chapter = chapter
+		card.ClassContext = ""
+	}
 }
 
 type FlashcardsArgs struct {
-	Wikipedia WikipediaArgs `embed:"wikipedia" prefix:"wikipedia-" group:"Wikipedia"`
+	Sources    []string       `default:"wikipedia,wiktionary,occlusion" help:"Sources to generate cards from, in order, for textbooks that don't list their own."`
+	Wikipedia  WikipediaArgs  `embed:"wikipedia" prefix:"wikipedia-" group:"Wikipedia"`
+	Wiktionary WiktionaryArgs `embed:"" prefix:"wiktionary-" group:"Wiktionary"`
+	Overview   OverviewArgs   `embed:"" prefix:"overview-" group:"AI Overview"`
+	Images     ImagesArgs     `embed:"" prefix:"images-" group:"Images"`
+	Redact     RedactArgs     `embed:"" prefix:"redact-" group:"Redaction"`
+
+	client *WikimediaClient `kong:"-"`
 }
 
 type WikipediaArgs struct {
-	Disable   bool   `help:"Don't generate cards using wikipedia."`
-	UserAgent string `help:"User-Agent field in making requests. If empty uses 'git config user.email'."`
+	Disable   bool          `help:"Don't generate cards using wikipedia."`
+	UserAgent string        `help:"User-Agent field in making requests to Wikipedia and Wiktionary. If empty uses 'git config user.email'."`
+	Interval  time.Duration `default:"100ms" help:"Minimum time between requests to Wikipedia and Wiktionary."`
+	Sentences int           `help:"Keep at most this many sentences of each article's summary. 0 keeps them all."`
+}
+
+type WiktionaryArgs struct {
+	Disable bool `help:"Don't generate cards using wiktionary."`
+}
+
+// Client returns a client for Wikimedia sites shared by everything using
+// them, so rate limiting applies across all of them.
+func (args *FlashcardsArgs) Client() *WikimediaClient {
+	if args.client == nil {
+		args.client = &WikimediaClient{
+			Contact:  args.Wikipedia.UserAgent,
+			Interval: args.Wikipedia.Interval,
+		}
+	}
+	return args.client
 }
 
-func (args *WikipediaArgs) AfterApply(ctx context.Context) error {
-	if args.Disable {
+func (args *FlashcardsArgs) AfterApply(ctx context.Context) error {
+	if args.Wikipedia.Disable && args.Wiktionary.Disable {
 		return nil
 	}
-	if args.UserAgent == "" {
+	if args.Wikipedia.UserAgent == "" {
 		rawBytes, err := exec.CommandContext(ctx, "git", "config", "user.email").Output()
 		if err != nil {
 			return fmt.Errorf("could not infer user agent: %w", err)
 		}
-		args.UserAgent = strings.TrimSpace(string(rawBytes))
+		args.Wikipedia.UserAgent = strings.TrimSpace(string(rawBytes))
 	}
 	return nil
 }
 
 type root struct {
-	Textbooks []Textbook `json:"textbooks" yaml:"textbooks"`
+	// Other textbook files (or directories, or globs) relative to this one.
+	Include []string `json:"include,omitempty" yaml:"include"`
+	// Styles of every textbook with the subject, by subject. Textbooks can
+	// override parts of it.
+	Subjects  map[string]Style `json:"subjects,omitempty" yaml:"subjects"`
+	Textbooks []Textbook       `json:"textbooks" yaml:"textbooks"`
 }
 
 type Textbook struct {
-	Name     string    `json:"name" yaml:"name"`
-	Subject  string    `json:"subject" yaml:"subject"`
-	Chapters []Chapter `json:"chapters" yaml:"chapters"`
+	Name    string `json:"name" yaml:"name"`
+	Subject string `json:"subject" yaml:"subject"`
+	// Sources to generate cards from, in order. Defaults to --sources.
+	Sources []string `json:"sources,omitempty" yaml:"sources"`
+	// Defaults for every term in the textbook.
+	Language  string    `json:"language,omitempty" yaml:"language"`
+	Bilingual string    `json:"bilingual,omitempty" yaml:"bilingual"`
+	Direction string    `json:"direction,omitempty" yaml:"direction"`
+	Style     Style     `json:"style,omitzero" yaml:"style"`
+	Chapters  []Chapter `json:"chapters" yaml:"chapters"`
 }
 
 type Chapter struct {
-	Number int    `json:"chapter" yaml"chapter"`
+	Number int    `json:"chapter" yaml:"chapter"`
+	Title  string `json:"title,omitempty" yaml:"title"`
 	Terms  []Term `json:"terms" yaml:"terms"`
 }
 
@@ -60,13 +166,68 @@ type Term struct {
 	Name      string   `json:"name" yaml:"name"`
 	Passages  []string `json:"passages,omitempty" yaml:"passages"`
 	Wikipedia []string `json:"wikipedia,omitempty" yaml:"wikipedia"`
+	// Words, optionally prefixed with the language to use (ex: es:gato).
+	Wiktionary []string `json:"wiktionary,omitempty" yaml:"wiktionary"`
+	// Language of articles and words without a prefix. Defaults to the
+	// textbook's, then DefaultLanguage.
+	Language string `json:"language,omitempty" yaml:"language"`
+	// Other language to also make cards in, pairing articles through their
+	// interlanguage links (ex: en).
+	Bilingual string `json:"bilingual,omitempty" yaml:"bilingual"`
+	// Which way to quiz the term: DirectionForward (the default),
+	// DirectionReverse or DirectionBoth.
+	Direction string `json:"direction,omitempty" yaml:"direction"`
+	// Diagram to make a card for each labelled region of.
+	Occlusion *Occlusion `json:"occlusion,omitempty" yaml:"occlusion"`
+}
+
+// inherit fills in settings the term leaves to its textbook.
+func (term Term) inherit(textbook Textbook) Term {
+	if term.Language == "" {
+		term.Language = textbook.Language
+	}
+	if term.Bilingual == "" {
+		term.Bilingual = textbook.Bilingual
+	}
+	if term.Direction == "" {
+		term.Direction = textbook.Direction
+	}
+	return term
+}
+
+// ReadFlashcards reads cards written by GenerateCMD.
+func ReadFlashcards(path string) ([]Flashcard, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var cards []Flashcard
+	if err := json.NewDecoder(file).Decode(&cards); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	for i := range cards {
+		cards[i].upgrade()
+	}
+	return cards, nil
 }
 
+// ParseTextbooks parses a single textbook file, ignoring its includes. See
+// LoadTextbooks to follow them.
 func ParseTextbooks(ctx context.Context, reader io.Reader) ([]Textbook, error) {
+	root, err := parseRoot(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+	return root.Textbooks, nil
+}
+
+func parseRoot(ctx context.Context, reader io.Reader) (root, error) {
 	var root root
 	decoder := yaml.NewDecoder(reader, yaml.DisallowUnknownField())
 	if err := decoder.DecodeContext(ctx, &root); err != nil {
-		return nil, fmt.Errorf("parsing yaml: %w", err)
+		return root, fmt.Errorf("parsing yaml: %w", err)
 	}
-	return root.Textbooks, nil
+	return root, nil
 }
@@ -1,7 +1,6 @@
 package flashcard
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,42 +9,70 @@ import (
 )
 
 type GenerateCMD struct {
-	File       []byte         `arg:"" type:"filecontent" help:"YAML file describing terms to make flashcards of."`
+	Files      []string       `arg:"" type:"path" help:"YAML files (or directories of them) describing terms to make flashcards of."`
 	Output     string         `short:"o" default:"out.json" type:"path" help:"File to write to."`
 	Flashcards FlashcardsArgs `embed:""`
 }
 
 func (config *GenerateCMD) Run(ctx context.Context, logger *slog.Logger) error {
-	textbooks, err := ParseTextbooks(ctx, bytes.NewReader(config.File))
+	textbooks, err := LoadTextbooks(ctx, config.Files...)
 	if err != nil {
 		return err
 	}
 	slog.Debug("got", "textbooks", textbooks)
 
-	wikipediaClient := &WikipediaClient{
-		Contact: config.Flashcards.Wikipedia.UserAgent,
+	// Check every textbook's sources before making any requests.
+	available := NewSources(&config.Flashcards)
+	sources := make([][]Source, len(textbooks))
+	for i, textbook := range textbooks {
+		names := config.Flashcards.Sources
+		if len(textbook.Sources) > 0 {
+			names = textbook.Sources
+		}
+		if sources[i], err = available.Ordered(names); err != nil {
+			return fmt.Errorf("textbook %s: %w", textbook.Name, err)
+		}
 	}
 
 	var flashcards []Flashcard
+	// Direction of the term each card came from, applied once every card has
+	// its overview so reverse cards share it.
+	var directions []string
 	var errs []error
-	for _, textbook := range textbooks {
+	for i, textbook := range textbooks {
 		for _, chapter := range textbook.Chapters {
 			for _, term := range chapter.Terms {
-				if !config.Flashcards.Wikipedia.Disable {
-					wikipedia, err := wikipediaClient.CreateFlashcards(ctx, term)
-					if err != nil {
-						errs = append(errs, fmt.Errorf("wikipedia: %w", err))
-					} else {
-						for _, card := range wikipedia {
-							card.ClassContext = fmt.Sprintf("Chapter: %d", chapter.Number)
-							flashcards = append(flashcards, card)
-						}
-					}
+				term := term.inherit(textbook)
+				cards, termErrs := NewFlashcardsFor(ctx, term, sources[i])
+				errs = append(errs, termErrs...)
+				for _, card := range cards {
+					card.Textbook = textbook.Name
+					card.Subject = textbook.Subject
+					card.Chapter = chapter.Number
+					card.ChapterTitle = chapter.Title
+					card.Style = textbook.Style
+					flashcards = append(flashcards, card)
+					directions = append(directions, term.Direction)
 				}
 			}
 		}
 	}
 
+	if config.Flashcards.Images.Mirror {
+		store := &ImageStore{
+			Dir:    config.Flashcards.Images.Dir,
+			Client: config.Flashcards.Client(),
+		}
+		errs = append(errs, MirrorImages(ctx, store, flashcards)...)
+	}
+
+	if config.Flashcards.Overview.Enable {
+		errs = append(errs, AddOverviews(ctx, config.Flashcards.Overview, flashcards, logger)...)
+	}
+
+	flashcards = ApplyDirections(flashcards, directions)
+	Redact(flashcards, config.Flashcards.Redact)
+
 	if len(errs) > 0 {
 		msgs := []string{}
 		for _, err := range errs {
@@ -69,9 +96,31 @@ func (config *GenerateCMD) Run(ctx context.Context, logger *slog.Logger) error {
 	return nil
 }
 
-func NewFlashcardsFor(ctx context.Context, term Term, features FlashcardsArgs) ([]Flashcard, []error) {
-	var cards []Flashcard
-	var errs []error
+// AddOverviews fills in AIOverview for cards with a description, in place.
+func AddOverviews(ctx context.Context, args OverviewArgs, cards []Flashcard, logger *slog.Logger) []error {
+	client, err := NewOverviewClient(args)
+	if err != nil {
+		return []error{fmt.Errorf("overview: %w", err)}
+	}
 
-	return cards, errs
+	var errs []error
+	for i, card := range cards {
+		if card.Description == "" || len(card.AIOverview) > 0 {
+			continue
+		}
+		overview, err := client.Overview(ctx, card)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("overview: %s: %w", card.Header, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		logger.Debug("added overview", "header", card.Header, "bullets", len(overview))
+		cards[i].AIOverview = overview
+	}
+	if err := client.Cache.Save(); err != nil {
+		errs = append(errs, fmt.Errorf("overview: saving cache: %w", err))
+	}
+	return errs
 }
@@ -14,6 +14,9 @@ type Image struct {
 	Source string `json:"source"`
 	Width  int    `json:"width"`
 	Height int    `json:"height"`
+	// Local copy of Source, if mirrored (see ImageStore).
+	Path   string `json:"path,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 func (i Image) Value() (driver.Value, error) {
@@ -37,3 +40,12 @@ func (i *Image) Scan(value interface{}) error {
 
 	return json.Unmarshal(bytes, i)
 }
+
+// Location is where the image can be loaded from: Source, or the local copy
+// for images only stored locally (ex: occlusions).
+func (i Image) Location() string {
+	if i.Source != "" {
+		return i.Source
+	}
+	return i.Path
+}
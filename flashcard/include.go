@@ -0,0 +1,246 @@
+package flashcard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrNoTextbooks = errors.New("no textbook files found")
+
+// ChapterConflictError is returned when two files define the same chapter of
+// a textbook.
+type ChapterConflictError struct {
+	Textbook string
+	Chapter  int
+	Files    [2]string
+}
+
+func (err *ChapterConflictError) Error() string {
+	return fmt.Sprintf("%s: chapter %d defined in both %s and %s",
+		err.Textbook, err.Chapter, err.Files[0], err.Files[1])
+}
+
+// TextbookFiles expands directories in paths to the YAML files they contain,
+// in lexical order.
+func TextbookFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yaml", ".yml":
+				if !entry.IsDir() {
+					files = append(files, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// LoadTextbooks parses every file (or directory of files) in paths, following
+// includes, and merges textbooks with the same name. Each file is read once
+// however many times it is referenced.
+func LoadTextbooks(ctx context.Context, paths ...string) ([]Textbook, error) {
+	files, err := TextbookFiles(paths)
+	if err != nil {
+		return nil, err
+	} else if len(files) == 0 {
+		return nil, ErrNoTextbooks
+	}
+
+	loader := textbookLoader{
+		seen:     map[string]bool{},
+		sources:  map[string]map[int]string{},
+		subjects: map[string]Style{},
+	}
+	for _, file := range files {
+		if err := loader.load(ctx, file); err != nil {
+			return nil, err
+		}
+	}
+	// Subjects may be styled in a file read after their textbooks.
+	for i, textbook := range loader.textbooks {
+		loader.textbooks[i].Style = textbook.Style.inherit(loader.subjects[textbook.Subject])
+	}
+	return loader.textbooks, nil
+}
+
+type textbookLoader struct {
+	textbooks []Textbook
+	seen      map[string]bool
+	// File each chapter came from, by textbook name then chapter number.
+	sources  map[string]map[int]string
+	subjects map[string]Style
+}
+
+func (loader *textbookLoader) load(ctx context.Context, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	} else if loader.seen[abs] {
+		return nil
+	}
+	loader.seen[abs] = true
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	root, err := parseRoot(ctx, file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	root.resolve(filepath.Dir(path))
+	for _, subject := range slices.Sorted(maps.Keys(root.Subjects)) {
+		merged := loader.subjects[subject]
+		if err := mergeStyle(&merged, root.Subjects[subject]); err != nil {
+			return fmt.Errorf("%s: subject %s: %w", path, subject, err)
+		}
+		loader.subjects[subject] = merged
+	}
+	for _, textbook := range root.Textbooks {
+		if err := loader.merge(path, textbook); err != nil {
+			return err
+		}
+	}
+
+	includes, err := root.includes(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, include := range includes {
+		if err := loader.load(ctx, include); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (loader *textbookLoader) merge(path string, textbook Textbook) error {
+	i := slices.IndexFunc(loader.textbooks, func(existing Textbook) bool {
+		return existing.Name == textbook.Name
+	})
+	if i < 0 {
+		loader.textbooks = append(loader.textbooks, Textbook{Name: textbook.Name})
+		loader.sources[textbook.Name] = map[int]string{}
+		i = len(loader.textbooks) - 1
+	}
+
+	merged := &loader.textbooks[i]
+	for _, setting := range []struct {
+		name   string
+		merged *string
+		value  string
+	}{
+		{"subject", &merged.Subject, textbook.Subject},
+		{"language", &merged.Language, textbook.Language},
+		{"bilingual", &merged.Bilingual, textbook.Bilingual},
+		{"direction", &merged.Direction, textbook.Direction},
+	} {
+		if *setting.merged == "" {
+			*setting.merged = setting.value
+		} else if setting.value != "" && setting.value != *setting.merged {
+			return fmt.Errorf("%s: textbook %s has %s %q, expected %q", path, textbook.Name, setting.name, setting.value, *setting.merged)
+		}
+	}
+	if err := mergeStyle(&merged.Style, textbook.Style); err != nil {
+		return fmt.Errorf("%s: textbook %s: %w", path, textbook.Name, err)
+	}
+	if len(merged.Sources) == 0 {
+		merged.Sources = textbook.Sources
+	} else if len(textbook.Sources) > 0 && !slices.Equal(textbook.Sources, merged.Sources) {
+		return fmt.Errorf("%s: textbook %s has sources %v, expected %v", path, textbook.Name, textbook.Sources, merged.Sources)
+	}
+
+	sources := loader.sources[textbook.Name]
+	for _, chapter := range textbook.Chapters {
+		if existing, ok := sources[chapter.Number]; ok {
+			return &ChapterConflictError{
+				Textbook: textbook.Name,
+				Chapter:  chapter.Number,
+				Files:    [2]string{existing, path},
+			}
+		}
+		sources[chapter.Number] = path
+		merged.Chapters = append(merged.Chapters, chapter)
+	}
+	return nil
+}
+
+// mergeStyle fills in merged from style, which may only repeat what merged
+// already has.
+func mergeStyle(merged *Style, style Style) error {
+	for _, setting := range []struct {
+		name   string
+		merged *string
+		value  string
+	}{
+		{"color", &merged.Color, style.Color},
+		{"author", &merged.Author, style.Author},
+		{"icon", &merged.Icon, style.Icon},
+		{"thumbnail", &merged.Thumbnail, style.Thumbnail},
+	} {
+		if *setting.merged == "" {
+			*setting.merged = setting.value
+		} else if setting.value != "" && setting.value != *setting.merged {
+			return fmt.Errorf("style has %s %q, expected %q", setting.name, setting.value, *setting.merged)
+		}
+	}
+	return nil
+}
+
+// resolve makes paths in the textbooks relative to dir, where they were
+// written.
+func (root root) resolve(dir string) {
+	for _, textbook := range root.Textbooks {
+		for _, chapter := range textbook.Chapters {
+			for _, term := range chapter.Terms {
+				if term.Occlusion != nil && !filepath.IsAbs(term.Occlusion.Image) {
+					term.Occlusion.Image = filepath.Join(dir, term.Occlusion.Image)
+				}
+			}
+		}
+	}
+}
+
+// includes resolves Include relative to dir.
+func (root root) includes(dir string) ([]string, error) {
+	var paths []string
+	for _, include := range root.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("include %s: %w", include, os.ErrNotExist)
+		}
+		paths = append(paths, matches...)
+	}
+	return TextbookFiles(paths)
+}
@@ -0,0 +1,304 @@
+package flashcard
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Schema is a JSON Schema describing textbook files for editor integration.
+//
+//go:embed textbooks.schema.json
+var Schema string
+
+type LintCMD struct {
+	Files  []string `arg:"" optional:"" type:"path" help:"YAML files (or directories of them) describing terms to check."`
+	Schema bool     `help:"Print the JSON Schema for textbook files instead."`
+}
+
+// LintError is returned when problems are found so the process exits non-zero.
+type LintError struct {
+	Problems int
+}
+
+func (err *LintError) Error() string {
+	return fmt.Sprintf("found %d problem(s)", err.Problems)
+}
+
+func (err *LintError) ExitCode() int {
+	return 1
+}
+
+func (config *LintCMD) Run(ctx context.Context, stdout io.Writer, logger *slog.Logger) error {
+	if config.Schema {
+		_, err := io.WriteString(stdout, Schema)
+		return err
+	} else if len(config.Files) == 0 {
+		return errors.New("no files to lint")
+	}
+
+	files, err := TextbookFiles(config.Files)
+	if err != nil {
+		return err
+	}
+
+	var problems int
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		for _, issue := range Lint(ctx, data, filepath.Dir(path)) {
+			problems++
+			fmt.Fprintf(stdout, "%s:%s\n", path, issue)
+		}
+	}
+
+	// Only worth merging once every file is fine on its own.
+	if problems == 0 {
+		if _, err := LoadTextbooks(ctx, files...); err != nil {
+			problems++
+			fmt.Fprintln(stdout, err)
+		}
+	}
+	if problems > 0 {
+		return &LintError{Problems: problems}
+	}
+	logger.Debug("no problems found", "files", len(config.Files))
+	return nil
+}
+
+// Issue is a single problem found in a textbook file.
+type Issue struct {
+	// Path to the offending node (ex: $.textbooks[0].chapters[1]).
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (issue Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s", issue.Line, issue.Column, issue.Message)
+}
+
+// Lint reports every problem found in a textbook file. Problems that stop the
+// file from being parsed are reported alone. Includes are resolved relative to
+// dir but not checked themselves.
+func Lint(ctx context.Context, data []byte, dir string) []Issue {
+	parsed, err := parseRoot(ctx, bytes.NewReader(data))
+	if err != nil {
+		issue := Issue{Line: 1, Column: 1, Message: err.Error()}
+		var yamlErr yaml.Error
+		if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+			issue.Line = yamlErr.GetToken().Position.Line
+			issue.Column = yamlErr.GetToken().Position.Column
+			issue.Message = yamlErr.GetMessage()
+		}
+		return []Issue{issue}
+	}
+
+	var issues []Issue
+	if len(parsed.Textbooks) == 0 && len(parsed.Include) == 0 && len(parsed.Subjects) == 0 {
+		issues = append(issues, Issue{Path: "$", Message: "no textbooks"})
+	}
+	for i, include := range parsed.Include {
+		if _, err := (root{Include: []string{include}}).includes(dir); err != nil {
+			issues = append(issues, Issue{Path: fmt.Sprintf("$.include[%d]", i), Message: err.Error()})
+		}
+	}
+	for _, subject := range slices.Sorted(maps.Keys(parsed.Subjects)) {
+		parsed.Subjects[subject].check(func(field string, format string, args ...any) {
+			path := fmt.Sprintf("$.subjects.%s.%s", subject, field)
+			issues = append(issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+		})
+	}
+	issues = append(issues, CheckTextbooks(parsed.Textbooks, dir)...)
+
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		// Already parsed above, so this should not happen.
+		return issues
+	}
+	for i := range issues {
+		issues[i].Line, issues[i].Column = position(file, issues[i].Path)
+	}
+	return issues
+}
+
+// CheckTextbooks finds mistakes that parse fine but would fail or produce
+// confusing cards later. Relative paths are resolved from dir. Issues have
+// their Path set but not their position.
+func CheckTextbooks(textbooks []Textbook, dir string) []Issue {
+	var issues []Issue
+	report := func(path string, format string, args ...any) {
+		issues = append(issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	language := func(path string, code string) {
+		if code != "" && !isLanguageCode(code) {
+			report(path, "%q is not a language code (ex: en)", code)
+		}
+	}
+	direction := func(path string, value string) {
+		if value != "" && !slices.Contains(Directions(), value) {
+			report(path, "unknown direction %q (expected one of %v)", value, Directions())
+		}
+	}
+
+	for i, textbook := range textbooks {
+		path := fmt.Sprintf("$.textbooks[%d]", i)
+		if strings.TrimSpace(textbook.Name) == "" {
+			report(path, "textbook has no name")
+		}
+		language(path+".language", textbook.Language)
+		language(path+".bilingual", textbook.Bilingual)
+		direction(path+".direction", textbook.Direction)
+		textbook.Style.check(func(field string, format string, args ...any) {
+			report(path+".style."+field, format, args...)
+		})
+		for j, source := range textbook.Sources {
+			if !slices.Contains(SourceNames(), source) {
+				report(fmt.Sprintf("%s.sources[%d]", path, j), "unknown source %q (expected one of %v)", source, SourceNames())
+			}
+		}
+
+		chapters := map[int]int{}
+		for j, chapter := range textbook.Chapters {
+			path := fmt.Sprintf("%s.chapters[%d]", path, j)
+			if chapter.Number <= 0 {
+				report(path+".chapter", "chapter number must be at least 1, got %d", chapter.Number)
+			} else if first, ok := chapters[chapter.Number]; ok {
+				report(path+".chapter", "chapter %d already defined at chapters[%d]", chapter.Number, first)
+			} else {
+				chapters[chapter.Number] = j
+			}
+			if len(chapter.Terms) == 0 {
+				report(path, "chapter %d has no terms", chapter.Number)
+			}
+
+			terms := map[string]int{}
+			for k, term := range chapter.Terms {
+				path := fmt.Sprintf("%s.terms[%d]", path, k)
+				name := strings.TrimSpace(term.Name)
+				if name == "" {
+					report(path+".name", "term has no name")
+				} else if first, ok := terms[strings.ToLower(name)]; ok {
+					report(path+".name", "duplicate term %q (first at terms[%d])", name, first)
+				} else {
+					terms[strings.ToLower(name)] = k
+				}
+				language(path+".language", term.Language)
+				language(path+".bilingual", term.Bilingual)
+				direction(path+".direction", term.Direction)
+				if len(term.Passages) == 0 && len(term.Wikipedia) == 0 && len(term.Wiktionary) == 0 && term.Occlusion == nil {
+					report(path, "term %q has no passages, wikipedia, wiktionary or occlusion", name)
+				}
+				if term.Occlusion != nil {
+					issues = append(issues, checkOcclusion(path+".occlusion", *term.Occlusion, dir)...)
+				}
+				for l, article := range term.Wikipedia {
+					if strings.TrimSpace(article) == "" {
+						report(fmt.Sprintf("%s.wikipedia[%d]", path, l), "empty wikipedia article")
+					}
+				}
+				for l, entry := range term.Wiktionary {
+					if strings.TrimSpace(entry) == "" {
+						report(fmt.Sprintf("%s.wiktionary[%d]", path, l), "empty wiktionary entry")
+					}
+				}
+				if code := cmp.Or(term.Language, textbook.Language); len(term.Wiktionary) > 0 && code != "" && code != WiktionaryEdition {
+					report(path+".wiktionary", "%v: got language %s (prefix foreign words instead, ex: es:gato)", ErrWiktionaryLanguage, code)
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func checkOcclusion(path string, occlusion Occlusion, dir string) []Issue {
+	var issues []Issue
+	report := func(path string, format string, args ...any) {
+		issues = append(issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var bounds image.Rectangle
+	file := occlusion.Image
+	if file == "" {
+		report(path, "occlusion has no image")
+	} else {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if config, err := imageConfig(file); err != nil {
+			report(path+".image", "%v", err)
+		} else {
+			bounds = image.Rect(0, 0, config.Width, config.Height)
+		}
+	}
+
+	if len(occlusion.Regions) == 0 {
+		report(path, "occlusion has no regions")
+	}
+	labels := map[string]int{}
+	for i, region := range occlusion.Regions {
+		path := fmt.Sprintf("%s.regions[%d]", path, i)
+		label := strings.TrimSpace(region.Label)
+		if label == "" {
+			report(path+".label", "region has no label")
+		} else if first, ok := labels[label]; ok {
+			report(path+".label", "duplicate label %q (first at regions[%d])", label, first)
+		} else {
+			labels[label] = i
+		}
+		rect := region.Rectangle()
+		if region.Rect[2] <= 0 || region.Rect[3] <= 0 {
+			report(path+".rect", "width and height must be positive, got %dx%d", region.Rect[2], region.Rect[3])
+		} else if !bounds.Empty() && !rect.In(bounds) {
+			report(path+".rect", "%v is outside of the %dx%d image", rect, bounds.Dx(), bounds.Dy())
+		}
+	}
+	return issues
+}
+
+func imageConfig(path string) (image.Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	return config, err
+}
+
+// position finds the line and column of path, falling back to its parents if
+// it does not exist (ex: a missing key).
+func position(file *ast.File, path string) (int, int) {
+	for path != "" {
+		if yamlPath, err := yaml.PathString(path); err == nil {
+			if node, err := yamlPath.FilterFile(file); err == nil && node != nil {
+				token := node.GetToken()
+				return token.Position.Line, token.Position.Column
+			}
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i <= 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 1, 1
+}
@@ -0,0 +1,110 @@
+package flashcard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+type ImagesArgs struct {
+	Mirror bool   `help:"Download thumbnails into --images-dir so cards don't depend on the original URL."`
+	Dir    string `default:"images" type:"path" help:"Directory to store downloaded images in, named by their SHA-256."`
+}
+
+// ImageStore keeps images in a directory by content (ex:
+// images/ab/abcdef....jpg), so the same image is only stored once.
+type ImageStore struct {
+	Dir    string
+	Client *WikimediaClient
+}
+
+// Mirror downloads image.Source into the store, returning image with its
+// Path and SHA256 set. Images already mirrored are returned as is.
+func (store *ImageStore) Mirror(ctx context.Context, image Image) (Image, error) {
+	if image.Source == "" {
+		return image, nil
+	} else if image.Path != "" {
+		if _, err := os.Stat(image.Path); err == nil {
+			return image, nil
+		}
+	}
+
+	response, err := store.Client.Do(ctx, "GET", image.Source)
+	if err != nil {
+		return image, err
+	}
+	defer response.Body.Close()
+
+	saved, err := store.Save(response.Body, imageExt(image.Source, response.Header.Get("Content-Type")))
+	if err != nil {
+		return image, fmt.Errorf("downloading %s: %w", image.Source, err)
+	}
+	image.Path = saved.Path
+	image.SHA256 = saved.SHA256
+	return image, nil
+}
+
+// Save stores the content of reader, returning an Image with only Path and
+// SHA256 set.
+func (store *ImageStore) Save(reader io.Reader, ext string) (Image, error) {
+	if err := os.MkdirAll(store.Dir, 0755); err != nil {
+		return Image{}, err
+	}
+	temp, err := os.CreateTemp(store.Dir, ".download-")
+	if err != nil {
+		return Image{}, err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(temp, hash), reader); err != nil {
+		return Image{}, err
+	} else if err := temp.Close(); err != nil {
+		return Image{}, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	destination := filepath.Join(store.Dir, sum[:2], sum+ext)
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return Image{}, err
+	} else if err := os.Rename(temp.Name(), destination); err != nil {
+		return Image{}, err
+	}
+	return Image{Path: destination, SHA256: sum}, nil
+}
+
+// imageExt guesses the extension of an image from its URL, then its type.
+func imageExt(source string, contentType string) string {
+	if parsed, err := url.Parse(source); err == nil && path.Ext(parsed.Path) != "" {
+		return path.Ext(parsed.Path)
+	}
+	if extensions, err := mime.ExtensionsByType(contentType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
+
+// MirrorImages mirrors the thumbnail of every card, in place.
+func MirrorImages(ctx context.Context, store *ImageStore, cards []Flashcard) []error {
+	var errs []error
+	for i, card := range cards {
+		image, err := store.Mirror(ctx, card.Thumbnail)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mirroring thumbnail for %s: %w", card.Header, err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		cards[i].Thumbnail = image
+	}
+	return errs
+}
@@ -0,0 +1,166 @@
+package flashcard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Kinds of cards (see Flashcard.Kind).
+const (
+	KindStandard  = ""
+	KindOcclusion = "occlusion"
+)
+
+var (
+	// Fill for the region being asked about.
+	OcclusionTarget = color.RGBA{R: 0xE7, G: 0x4C, B: 0x3C, A: 0xFF}
+	// Fill for the other regions, so they don't give the answer away.
+	OcclusionOther = color.RGBA{R: 0xBD, G: 0xC3, B: 0xC7, A: 0xFF}
+	// OcclusionBorder is the outline width of revealed regions in pixels.
+	OcclusionBorder = 3
+)
+
+// Occlusion is a diagram with labelled regions to hide (ex: anatomy).
+type Occlusion struct {
+	// Path of the image, relative to the textbook file.
+	Image   string   `json:"image" yaml:"image"`
+	Regions []Region `json:"regions" yaml:"regions"`
+}
+
+type Region struct {
+	Label string `json:"label" yaml:"label"`
+	// X, Y, width and height in pixels from the top left of the image.
+	Rect [4]int `json:"rect" yaml:"rect"`
+}
+
+func (region Region) Rectangle() image.Rectangle {
+	x, y, width, height := region.Rect[0], region.Rect[1], region.Rect[2], region.Rect[3]
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// OcclusionSource makes a card for each region of a term's diagram. The
+// prompt has the region hidden and the answer shows it outlined.
+type OcclusionSource struct {
+	Store *ImageStore
+}
+
+func init() {
+	RegisterSource(KindOcclusion, func(args *FlashcardsArgs, client *WikimediaClient) Source {
+		return &OcclusionSource{Store: &ImageStore{Dir: args.Images.Dir, Client: client}}
+	})
+}
+
+func (source *OcclusionSource) Name() string {
+	return KindOcclusion
+}
+
+func (source *OcclusionSource) Supports(term Term) bool {
+	return term.Occlusion != nil
+}
+
+func (source *OcclusionSource) Generate(ctx context.Context, term Term) ([]Flashcard, error) {
+	diagram, err := LoadImage(term.Occlusion.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []Flashcard
+	var errs error
+	for i, region := range term.Occlusion.Regions {
+		if !region.Rectangle().In(diagram.Bounds()) {
+			errs = errors.Join(errs, fmt.Errorf("%s: region %q is outside of the image", term.Occlusion.Image, region.Label))
+			continue
+		}
+		masked, err := source.save(Mask(diagram, term.Occlusion.Regions, i))
+		if err != nil {
+			return cards, errors.Join(errs, err)
+		}
+		revealed, err := source.save(Reveal(diagram, region))
+		if err != nil {
+			return cards, errors.Join(errs, err)
+		}
+		cards = append(cards, Flashcard{
+			Header:      term.Name,
+			Description: region.Label,
+			Origin:      fmt.Sprintf("%s#%s", filepath.Base(term.Occlusion.Image), region.Label),
+			Kind:        KindOcclusion,
+			Thumbnail:   masked,
+			Reveal:      revealed,
+		})
+	}
+	return cards, errs
+}
+
+func (source *OcclusionSource) save(rendered image.Image) (Image, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, rendered); err != nil {
+		return Image{}, err
+	}
+	saved, err := source.Store.Save(&buffer, ".png")
+	if err != nil {
+		return Image{}, fmt.Errorf("saving image: %w", err)
+	}
+	saved.Width = rendered.Bounds().Dx()
+	saved.Height = rendered.Bounds().Dy()
+	return saved, nil
+}
+
+func LoadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoded, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return decoded, nil
+}
+
+// Mask hides every region, the one at target in its own colour.
+func Mask(diagram image.Image, regions []Region, target int) image.Image {
+	canvas := copyImage(diagram)
+	for i, region := range regions {
+		fill := OcclusionOther
+		if i == target {
+			fill = OcclusionTarget
+		}
+		draw.Draw(canvas, region.Rectangle(), image.NewUniform(fill), image.Point{}, draw.Src)
+	}
+	return canvas
+}
+
+// Reveal outlines region on the original image.
+func Reveal(diagram image.Image, region Region) image.Image {
+	canvas := copyImage(diagram)
+	outer := region.Rectangle()
+	inner := outer.Inset(OcclusionBorder)
+	border := image.NewUniform(OcclusionTarget)
+	for _, edge := range []image.Rectangle{
+		image.Rect(outer.Min.X, outer.Min.Y, outer.Max.X, inner.Min.Y),
+		image.Rect(outer.Min.X, inner.Max.Y, outer.Max.X, outer.Max.Y),
+		image.Rect(outer.Min.X, outer.Min.Y, inner.Min.X, outer.Max.Y),
+		image.Rect(inner.Max.X, outer.Min.Y, outer.Max.X, outer.Max.Y),
+	} {
+		draw.Draw(canvas, edge, border, image.Point{}, draw.Src)
+	}
+	return canvas
+}
+
+func copyImage(src image.Image) *image.RGBA {
+	canvas := image.NewRGBA(src.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), src, src.Bounds().Min, draw.Src)
+	return canvas
+}
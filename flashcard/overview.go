@@ -0,0 +1,249 @@
+package flashcard
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MaxOverviewBullets caps the bullet points kept from a response. The prompt
+// asks for 3 to 5 but models don't always listen.
+const MaxOverviewBullets = 5
+
+var ErrNoBullets = errors.New("response had no bullet points")
+
+//go:embed overview.tmpl
+var defaultOverviewPrompt string
+
+type OverviewArgs struct {
+	Enable  bool          `help:"Fill in AI summaries using an OpenAI compatible chat completions endpoint (ex: Ollama, llama.cpp)."`
+	URL     string        `name:"url" default:"http://localhost:11434/v1" help:"Base URL of the API. /chat/completions is appended."`
+	Model   string        `default:"llama3.2" help:"Model to use."`
+	APIKey  string        `name:"api-key" env:"FISHY_LLM_API_KEY" secret:"" help:"Bearer token, if the server needs one."`
+	Prompt  string        `type:"existingfile" help:"text/template file rendering the prompt for a card. Defaults to a built in prompt."`
+	Cache   string        `default:".fishy-overview.json" type:"path" help:"File caching responses by model and prompt. Disabled if empty."`
+	Timeout time.Duration `default:"2m" help:"Timeout for each request."`
+}
+
+// OverviewClient summarizes cards into a few bullet points with an LLM.
+type OverviewClient struct {
+	URL    string
+	Model  string
+	APIKey string
+	Prompt *template.Template
+	// Optional.
+	Cache  *OverviewCache
+	Client *http.Client
+}
+
+func NewOverviewClient(args OverviewArgs) (*OverviewClient, error) {
+	prompt := defaultOverviewPrompt
+	if args.Prompt != "" {
+		content, err := os.ReadFile(args.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("reading prompt: %w", err)
+		}
+		prompt = string(content)
+	}
+	parsed, err := template.New("prompt").Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("parsing prompt: %w", err)
+	}
+
+	var cache *OverviewCache
+	if args.Cache != "" {
+		cache, err = LoadOverviewCache(args.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("loading cache: %w", err)
+		}
+	}
+	return &OverviewClient{
+		URL:    args.URL,
+		Model:  args.Model,
+		APIKey: args.APIKey,
+		Prompt: parsed,
+		Cache:  cache,
+		Client: &http.Client{Timeout: args.Timeout},
+	}, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Overview returns bullet points summarizing card.
+func (client *OverviewClient) Overview(ctx context.Context, card Flashcard) ([]string, error) {
+	var prompt strings.Builder
+	if err := client.Prompt.Execute(&prompt, card); err != nil {
+		return nil, fmt.Errorf("rendering prompt: %w", err)
+	}
+
+	key := cacheKey(client.Model, prompt.String())
+	if bullets, ok := client.Cache.Get(key); ok {
+		return bullets, nil
+	}
+
+	content, err := client.complete(ctx, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+	bullets := ParseBullets(content)
+	if len(bullets) == 0 {
+		return nil, ErrNoBullets
+	} else if len(bullets) > MaxOverviewBullets {
+		bullets = bullets[:MaxOverviewBullets]
+	}
+	client.Cache.Put(key, bullets)
+	return bullets, nil
+}
+
+func (client *OverviewClient) complete(ctx context.Context, prompt string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:       client.Model,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url := strings.TrimSuffix(client.URL, "/") + "/chat/completions"
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if client.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+client.APIKey)
+	}
+
+	httpClient := client.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("making request to %s: %w", url, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("got %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	var parsed chatResponse
+	if err := json.NewDecoder(response.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("reading body: %w", err)
+	} else if len(parsed.Choices) == 0 {
+		return "", errors.New("response had no choices")
+	}
+	return parsed.Choices[0].Message.Content, nil
+}
+
+var bulletPrefix = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// ParseBullets returns the bullet (or numbered) points in content, without
+// their markers or any markdown emphasis.
+func ParseBullets(content string) []string {
+	var bullets []string
+	for _, line := range strings.Split(content, "\n") {
+		prefix := bulletPrefix.FindString(line)
+		if prefix == "" {
+			continue
+		}
+		bullet := strings.TrimSpace(strings.ReplaceAll(line[len(prefix):], "**", ""))
+		if bullet != "" {
+			bullets = append(bullets, bullet)
+		}
+	}
+	return bullets
+}
+
+func cacheKey(model string, prompt string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// OverviewCache remembers responses between runs so regenerating cards does
+// not ask the model again. A nil cache stores nothing.
+type OverviewCache struct {
+	path    string
+	entries map[string][]string
+	dirty   bool
+}
+
+// LoadOverviewCache reads the cache at path, which need not exist yet.
+func LoadOverviewCache(path string) (*OverviewCache, error) {
+	cache := &OverviewCache{
+		path:    path,
+		entries: map[string][]string{},
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &cache.entries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+func (cache *OverviewCache) Get(key string) ([]string, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	bullets, ok := cache.entries[key]
+	return bullets, ok
+}
+
+func (cache *OverviewCache) Put(key string, bullets []string) {
+	if cache == nil {
+		return
+	}
+	cache.entries[key] = bullets
+	cache.dirty = true
+}
+
+// Save writes the cache back if anything was added.
+func (cache *OverviewCache) Save() error {
+	if cache == nil || !cache.dirty {
+		return nil
+	}
+	content, err := json.MarshalIndent(cache.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cache.path, content, 0644); err != nil {
+		return err
+	}
+	cache.dirty = false
+	return nil
+}
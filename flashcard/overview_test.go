@@ -0,0 +1,205 @@
+package flashcard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var photosynthesis = Flashcard{
+	Header:       "Photosynthesis",
+	Description:  "Photosynthesis is a process used by plants to convert light energy into chemical energy.",
+	Textbook:     "Campbell Biology",
+	Subject:      "Biology",
+	ChapterTitle: "Photosynthesis",
+}
+
+// llm stands in for a chat completions endpoint, replying with status and
+// content to every request.
+type llm struct {
+	*httptest.Server
+	Requests atomic.Int64
+	// Last request.
+	Path          string
+	Authorization string
+	Body          chatRequest
+}
+
+func newLLM(t *testing.T, status int, content string) *llm {
+	t.Helper()
+	stub := &llm{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.Requests.Add(1)
+		stub.Path = r.URL.Path
+		stub.Authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&stub.Body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if status != http.StatusOK {
+			http.Error(w, content, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{
+				map[string]any{"message": chatMessage{Role: "assistant", Content: content}},
+			},
+		})
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func overviewClient(t *testing.T, url string, cache string) *OverviewClient {
+	t.Helper()
+	client, err := NewOverviewClient(OverviewArgs{
+		URL:    url + "/v1/",
+		Model:  "llama3.2",
+		APIKey: "secret",
+		Cache:  cache,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestOverview(t *testing.T) {
+	stub := newLLM(t, http.StatusOK, "Here you go:\n- Happens in **chloroplasts**.\n- Releases oxygen.\n\n- Needs light.")
+	client := overviewClient(t, stub.URL, "")
+
+	bullets, err := client.Overview(context.Background(), photosynthesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Happens in chloroplasts.", "Releases oxygen.", "Needs light."}; !slices.Equal(bullets, want) {
+		t.Errorf("got %q, want %q", bullets, want)
+	}
+
+	if stub.Path != "/v1/chat/completions" {
+		t.Errorf("got path %s", stub.Path)
+	}
+	if stub.Authorization != "Bearer secret" {
+		t.Errorf("got authorization %q", stub.Authorization)
+	}
+	if stub.Body.Model != "llama3.2" || len(stub.Body.Messages) != 1 || stub.Body.Messages[0].Role != "user" {
+		t.Fatalf("got request %+v", stub.Body)
+	}
+	prompt := stub.Body.Messages[0].Content
+	for _, want := range []string{
+		"Summarize this Biology flashcard",
+		"Term: Photosynthesis\n",
+		"Textbook: Campbell Biology\n",
+		"Chapter: Photosynthesis\n",
+		"Description: " + photosynthesis.Description,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestOverviewCache(t *testing.T) {
+	stub := newLLM(t, http.StatusOK, "- Happens in chloroplasts.")
+	path := filepath.Join(t.TempDir(), "cache.json")
+	client := overviewClient(t, stub.URL, path)
+
+	ctx := context.Background()
+	for range 2 {
+		if _, err := client.Overview(ctx, photosynthesis); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := stub.Requests.Load(); got != 1 {
+		t.Errorf("made %d requests for the same card, want 1", got)
+	}
+	if err := client.Cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later run reads the cache from disk.
+	down := newLLM(t, http.StatusInternalServerError, "should not be asked")
+	client = overviewClient(t, down.URL, path)
+	bullets, err := client.Overview(ctx, photosynthesis)
+	if err != nil {
+		t.Fatal(err)
+	} else if !slices.Equal(bullets, []string{"Happens in chloroplasts."}) {
+		t.Errorf("got %q from cache", bullets)
+	}
+	if got := down.Requests.Load(); got != 0 {
+		t.Errorf("made %d requests for a cached card, want 0", got)
+	}
+
+	// Other models have their own entries.
+	client.Model = "mistral"
+	client.Overview(ctx, photosynthesis)
+	if got := down.Requests.Load(); got != 1 {
+		t.Errorf("made %d requests for another model, want 1", got)
+	}
+}
+
+func TestOverviewErrors(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Status  int
+		Content string
+		Err     string
+		Is      error
+	}{
+		{Name: "server error", Status: http.StatusInternalServerError, Content: "model crashed", Err: "got 500 Internal Server Error: model crashed"},
+		{Name: "not found", Status: http.StatusNotFound, Content: "model not found", Err: "got 404 Not Found: model not found"},
+		{Name: "no bullets", Status: http.StatusOK, Content: "Photosynthesis makes sugar.", Is: ErrNoBullets},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			stub := newLLM(t, test.Status, test.Content)
+			path := filepath.Join(t.TempDir(), "cache.json")
+			client := overviewClient(t, stub.URL, path)
+
+			_, err := client.Overview(context.Background(), photosynthesis)
+			if err == nil {
+				t.Fatal("expected an error")
+			} else if test.Err != "" && err.Error() != test.Err {
+				t.Errorf("got %q, want %q", err, test.Err)
+			} else if test.Is != nil && !errors.Is(err, test.Is) {
+				t.Errorf("got %v, want %v", err, test.Is)
+			}
+
+			// Failures are asked again next time.
+			if _, ok := client.Cache.Get(cacheKey(client.Model, stub.Body.Messages[0].Content)); ok {
+				t.Error("failure was cached")
+			}
+		})
+	}
+}
+
+func TestParseBullets(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Content  string
+		Expected []string
+	}{
+		{Name: "dashes", Content: "- one\n- two", Expected: []string{"one", "two"}},
+		{Name: "markers", Content: "* one\n• two\n1. three\n2) four", Expected: []string{"one", "two", "three", "four"}},
+		{Name: "indented", Content: "  - one\n\t- two", Expected: []string{"one", "two"}},
+		{Name: "emphasis", Content: "- **Light** reactions", Expected: []string{"Light reactions"}},
+		{Name: "prose skipped", Content: "Sure! Here are the points:\n- one\nHope this helps.", Expected: []string{"one"}},
+		{Name: "empty bullets", Content: "- \n-   **** \n- one", Expected: []string{"one"}},
+		{Name: "no marker space", Content: "-one\n1.5 grams"},
+		{Name: "empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := ParseBullets(test.Content); !slices.Equal(got, test.Expected) {
+				t.Errorf("got %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
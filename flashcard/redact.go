@@ -0,0 +1,183 @@
+package flashcard
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Cards Redact can apply to (see RedactArgs.Cards).
+const (
+	RedactNone      = "none"
+	RedactForward   = "forward"
+	RedactReverse   = "reverse"
+	RedactOcclusion = "occlusion"
+)
+
+type RedactArgs struct {
+	Cards []string `default:"reverse" enum:"none,forward,reverse,occlusion" help:"Cards to hide the term in the description and AI overview of (${enum}). none disables redaction."`
+	Mask  string   `default:"_____" help:"Text the term is replaced with."`
+}
+
+// Enabled reports whether any cards are redacted, so sources know whether to
+// look up aliases.
+func (args RedactArgs) Enabled() bool {
+	return slices.ContainsFunc(args.Cards, func(kind string) bool {
+		return kind != RedactNone
+	})
+}
+
+// Redact hides each card's header, its inflections and its aliases from its
+// description and AI overview, in place, for the cards args applies to.
+func Redact(cards []Flashcard, args RedactArgs) {
+	for i, card := range cards {
+		if !slices.Contains(args.Cards, card.redactAs()) {
+			continue
+		}
+		terms := card.redactions()
+		cards[i].Description = RedactTerms(card.Description, args.Mask, terms...)
+		// The overview is shared with the forward copy of reversed cards.
+		cards[i].AIOverview = nil
+		for _, line := range card.AIOverview {
+			cards[i].AIOverview = append(cards[i].AIOverview, RedactTerms(line, args.Mask, terms...))
+		}
+	}
+}
+
+func (card Flashcard) redactAs() string {
+	switch {
+	case card.Kind == KindOcclusion:
+		return RedactOcclusion
+	case card.Reversed():
+		return RedactReverse
+	default:
+		return RedactForward
+	}
+}
+
+// redactions returns every form of the header to hide. Inflections are only
+// guessed for English.
+func (card Flashcard) redactions() []string {
+	terms := append([]string{card.Header}, card.aliases...)
+	language, _, _ := strings.Cut(card.Language, "/")
+	if language != "" && language != "en" {
+		return terms
+	}
+	var forms []string
+	for _, term := range terms {
+		forms = append(forms, Inflections(term)...)
+	}
+	return forms
+}
+
+// RedactTerms replaces whole word occurrences of terms in text with mask,
+// ignoring case. Longer terms win, so a multi-word term is masked once rather
+// than word by word, and spaces in a term match any whitespace or hyphens.
+func RedactTerms(text string, mask string, terms ...string) string {
+	var alternatives []string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		alternative := strings.Join(strings.Fields(regexp.QuoteMeta(term)), `[\s-]+`)
+		if !slices.Contains(alternatives, alternative) {
+			alternatives = append(alternatives, alternative)
+		}
+	}
+	if len(alternatives) == 0 {
+		return text
+	}
+	slices.SortStableFunc(alternatives, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+
+	// RE2 has no lookaround and \b only knows ASCII. The end of a word is
+	// matched, so a shorter term is tried when a longer one runs into a word,
+	// and the start is checked by hand so the character before a term is not
+	// consumed by the previous match.
+	pattern := regexp.MustCompile(`(?i)(` + strings.Join(alternatives, "|") + `)(?:$|[^\p{L}\p{N}])`)
+	var builder strings.Builder
+	last := 0
+	for pos := 0; pos < len(text); {
+		match := pattern.FindStringSubmatchIndex(text[pos:])
+		if match == nil {
+			break
+		}
+		start, end := pos+match[2], pos+match[3]
+		if previous, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(previous) {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			pos = start + size
+			continue
+		}
+		builder.WriteString(text[last:start])
+		builder.WriteString(mask)
+		last, pos = end, end
+	}
+	builder.WriteString(text[last:])
+	return builder.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// Inflections returns term with guesses at the English plural and verb forms
+// of its last word (ex: nucleus, nuclei, nucleuses). Guesses that are not
+// words are harmless since they never match.
+func Inflections(term string) []string {
+	term = strings.TrimSpace(term)
+	prefix, word := "", term
+	if i := strings.LastIndexAny(term, " -"); i >= 0 {
+		prefix, word = term[:i+1], term[i+1:]
+	}
+	lower := strings.ToLower(word)
+	if len(lower) < 3 || strings.IndexFunc(lower, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+		return []string{term}
+	}
+	stem := func(n int) string {
+		return word[:len(word)-n]
+	}
+	consonantY := strings.HasSuffix(lower, "y") && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2]))
+
+	var forms []string
+	// Latin and Greek plurals, common in science.
+	switch {
+	case strings.HasSuffix(lower, "is"):
+		forms = append(forms, stem(2)+"es")
+	case strings.HasSuffix(lower, "us"):
+		forms = append(forms, stem(2)+"i")
+	case strings.HasSuffix(lower, "um"), strings.HasSuffix(lower, "on"):
+		forms = append(forms, stem(2)+"a")
+	case strings.HasSuffix(lower, "a"):
+		forms = append(forms, word+"e")
+	}
+	// Regular plurals and third person.
+	switch {
+	case consonantY:
+		forms = append(forms, stem(1)+"ies")
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		forms = append(forms, word+"es")
+	default:
+		forms = append(forms, word+"s")
+	}
+	// Past tense and participles.
+	switch {
+	case consonantY:
+		forms = append(forms, stem(1)+"ied", word+"ing")
+	case strings.HasSuffix(lower, "e"):
+		forms = append(forms, word+"d", stem(1)+"ing")
+	default:
+		forms = append(forms, word+"ed", word+"ing")
+	}
+
+	inflections := []string{term}
+	for _, form := range forms {
+		inflections = append(inflections, prefix+form)
+	}
+	return inflections
+}
@@ -0,0 +1,171 @@
+package flashcard
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedactTerms(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Text     string
+		Terms    []string
+		Expected string
+	}{
+		{
+			Name:     "start of extract",
+			Text:     "Photosynthesis is a process used by plants.",
+			Terms:    []string{"Photosynthesis"},
+			Expected: "_____ is a process used by plants.",
+		},
+		{
+			Name:     "case",
+			Text:     "PHOTOSYNTHESIS, or photosynthesis, or Photosynthesis.",
+			Terms:    []string{"photosynthesis"},
+			Expected: "_____, or _____, or _____.",
+		},
+		{
+			Name:     "punctuation",
+			Text:     `("Mitosis"), mitosis; mitosis: mitosis! [mitosis]`,
+			Terms:    []string{"Mitosis"},
+			Expected: `("_____"), _____; _____: _____! [_____]`,
+		},
+		{
+			Name:     "whole words only",
+			Text:     "A cell is not a cellular cellphone or a photocell.",
+			Terms:    []string{"cell"},
+			Expected: "A _____ is not a cellular cellphone or a photocell.",
+		},
+		{
+			Name:     "multi-word",
+			Text:     "The Cell   membrane (or cell-membrane) surrounds the cell.",
+			Terms:    []string{"cell membrane", "cell"},
+			Expected: "The _____ (or _____) surrounds the _____.",
+		},
+		{
+			Name:     "multi-word prefix of a longer word",
+			Text:     "A plant cellular wall is not a plant cell.",
+			Terms:    []string{"plant cell", "plant"},
+			Expected: "A _____ cellular wall is not a _____.",
+		},
+		{
+			Name:     "adjacent",
+			Text:     "cell cell,cell",
+			Terms:    []string{"cell"},
+			Expected: "_____ _____,_____",
+		},
+		{
+			Name:     "unicode",
+			Text:     "Ångström units, not Ångströms or xÅngström.",
+			Terms:    []string{"ångström"},
+			Expected: "_____ units, not Ångströms or xÅngström.",
+		},
+		{
+			Name:     "regexp characters",
+			Text:     "C++ (language) is not C.",
+			Terms:    []string{"C++ (language)"},
+			Expected: "_____ is not C.",
+		},
+		{
+			Name:     "no terms",
+			Text:     "Nothing to hide.",
+			Terms:    []string{"", "  "},
+			Expected: "Nothing to hide.",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := RedactTerms(test.Text, "_____", test.Terms...); got != test.Expected {
+				t.Errorf("got %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestInflections(t *testing.T) {
+	tests := []struct {
+		Term     string
+		Expected []string
+	}{
+		{Term: "cell", Expected: []string{"cell", "cells", "celled", "celling"}},
+		{Term: "nucleus", Expected: []string{"nucleus", "nuclei", "nucleuses", "nucleused", "nucleusing"}},
+		{Term: "mitochondrion", Expected: []string{"mitochondrion", "mitochondria", "mitochondrions", "mitochondrioned", "mitochondrioning"}},
+		{Term: "hypothesis", Expected: []string{"hypothesis", "hypotheses", "hypothesises", "hypothesised", "hypothesising"}},
+		{Term: "vertebra", Expected: []string{"vertebra", "vertebrae", "vertebras", "vertebraed", "vertebraing"}},
+		{Term: "Enzyme", Expected: []string{"Enzyme", "Enzymes", "Enzymed", "Enzyming"}},
+		{Term: "copy", Expected: []string{"copy", "copies", "copied", "copying"}},
+		{Term: "flux", Expected: []string{"flux", "fluxes", "fluxed", "fluxing"}},
+		{Term: "cell membrane", Expected: []string{"cell membrane", "cell membranes", "cell membraned", "cell membraning"}},
+		{Term: "ion", Expected: []string{"ion", "ia", "ions", "ioned", "ioning"}},
+		{Term: "pH", Expected: []string{"pH"}},
+		{Term: "DNA", Expected: []string{"DNA", "DNAe", "DNAs", "DNAed", "DNAing"}},
+		{Term: "Ångström", Expected: []string{"Ångström"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Term, func(t *testing.T) {
+			if got := Inflections(test.Term); !slices.Equal(got, test.Expected) {
+				t.Errorf("got %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cards := []Flashcard{
+		{Header: "Enzyme", Description: "Enzymes are proteins. An enzyme speeds up reactions."},
+		{Header: "Enzyme", Description: "Enzymes are proteins.", Direction: DirectionReverse},
+		{Header: "Ribosome", Description: "Ribosomes make proteins.", Direction: DirectionReverse, aliases: []string{"Ribosomal subunit"}},
+		{Header: "Enzyme", Description: "Les enzymes sont des protéines.", Language: "fr", Direction: DirectionReverse},
+		{Header: "Enzima", Description: "Una enzima.", Language: "es/en", Direction: DirectionReverse},
+	}
+	Redact(cards, RedactArgs{Cards: []string{RedactReverse}, Mask: "___"})
+
+	expected := []string{
+		// Forward cards show the term anyway.
+		"Enzymes are proteins. An enzyme speeds up reactions.",
+		"___ are proteins.",
+		"___ make proteins.",
+		// Inflections are only guessed for English.
+		"Les enzymes sont des protéines.",
+		"Una ___.",
+	}
+	for i, card := range cards {
+		if card.Description != expected[i] {
+			t.Errorf("card %d: got %q, want %q", i, card.Description, expected[i])
+		}
+	}
+}
+
+func TestRedactOverview(t *testing.T) {
+	forward := Flashcard{
+		Header:      "Enzyme",
+		Description: "A protein.",
+		AIOverview:  []string{"Enzymes speed up reactions.", "Made of amino acids."},
+	}
+	reverse := forward
+	reverse.Direction = DirectionReverse
+	cards := []Flashcard{forward, reverse}
+	Redact(cards, RedactArgs{Cards: []string{RedactReverse}, Mask: "___"})
+
+	if want := []string{"___ speed up reactions.", "Made of amino acids."}; !slices.Equal(cards[1].AIOverview, want) {
+		t.Errorf("got overview %q, want %q", cards[1].AIOverview, want)
+	}
+	if !slices.Equal(cards[0].AIOverview, forward.AIOverview) {
+		t.Errorf("forward overview changed: %q", cards[0].AIOverview)
+	}
+}
+
+func TestRedactNone(t *testing.T) {
+	if args := (RedactArgs{Cards: []string{RedactNone}}); args.Enabled() {
+		t.Error("none should disable redaction")
+	}
+	if args := (RedactArgs{Cards: []string{RedactForward}}); !args.Enabled() {
+		t.Error("forward should enable redaction")
+	}
+
+	cards := []Flashcard{{Header: "Enzyme", Description: "Enzymes are proteins.", Direction: DirectionReverse}}
+	Redact(cards, RedactArgs{Cards: []string{RedactNone}, Mask: "___"})
+	if cards[0].Description != "Enzymes are proteins." {
+		t.Errorf("got %q with redaction disabled", cards[0].Description)
+	}
+}
@@ -0,0 +1,89 @@
+package flashcard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Source makes cards for the terms it supports (ex: terms with wikipedia
+// articles).
+type Source interface {
+	Name() string
+	Supports(term Term) bool
+	Generate(ctx context.Context, term Term) ([]Flashcard, error)
+}
+
+// SourceFactory builds a source from the command line. It returns nil if the
+// source is disabled. Sources using Wikimedia sites should share client.
+type SourceFactory func(args *FlashcardsArgs, client *WikimediaClient) Source
+
+var sourceFactories = map[string]SourceFactory{}
+
+// RegisterSource makes a source available to textbooks by name. It is meant to
+// be called from init and panics on duplicate names.
+func RegisterSource(name string, factory SourceFactory) {
+	if _, ok := sourceFactories[name]; ok {
+		panic(fmt.Sprintf("source %s already registered", name))
+	}
+	sourceFactories[name] = factory
+}
+
+// SourceNames returns every registered source, sorted.
+func SourceNames() []string {
+	var names []string
+	for name := range sourceFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sources builds every enabled source once so they can be looked up by name.
+type Sources map[string]Source
+
+func NewSources(args *FlashcardsArgs) Sources {
+	client := args.Client()
+	sources := Sources{}
+	for name, factory := range sourceFactories {
+		if source := factory(args, client); source != nil {
+			sources[name] = source
+		}
+	}
+	return sources
+}
+
+// Ordered returns the enabled sources in names, in order. Names must be
+// registered.
+func (sources Sources) Ordered(names []string) ([]Source, error) {
+	var ordered []Source
+	seen := map[string]bool{}
+	for _, name := range names {
+		if _, ok := sourceFactories[name]; !ok {
+			return nil, fmt.Errorf("unknown source %q (expected one of %v)", name, SourceNames())
+		}
+		if source, ok := sources[name]; ok && !seen[name] {
+			seen[name] = true
+			ordered = append(ordered, source)
+		}
+	}
+	return ordered, nil
+}
+
+// NewFlashcardsFor makes cards for term from every source (in order) that
+// supports it.
+func NewFlashcardsFor(ctx context.Context, term Term, sources []Source) ([]Flashcard, []error) {
+	var cards []Flashcard
+	var errs []error
+	for _, source := range sources {
+		if !source.Supports(term) {
+			continue
+		}
+		generated, err := source.Generate(ctx, term)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+		cards = append(cards, generated...)
+	}
+	return cards, errs
+}
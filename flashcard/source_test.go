@@ -0,0 +1,47 @@
+package flashcard
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// listSource can't be compared with ==.
+type listSource struct {
+	name  string
+	terms []string
+}
+
+func (source listSource) Name() string {
+	return source.name
+}
+
+func (source listSource) Supports(term Term) bool {
+	return slices.Contains(source.terms, term.Name)
+}
+
+func (source listSource) Generate(ctx context.Context, term Term) ([]Flashcard, error) {
+	return []Flashcard{{Header: term.Name}}, nil
+}
+
+func TestOrdered(t *testing.T) {
+	sources := Sources{
+		"wikipedia":  listSource{name: "wikipedia", terms: []string{"Cell"}},
+		"wiktionary": listSource{name: "wiktionary", terms: []string{"Cell"}},
+	}
+	ordered, err := sources.Ordered([]string{"wiktionary", "occlusion", "wikipedia", "wiktionary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, source := range ordered {
+		names = append(names, source.Name())
+	}
+	if want := []string{"wiktionary", "wikipedia"}; !slices.Equal(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+
+	if _, err := sources.Ordered([]string{"encyclopedia"}); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}
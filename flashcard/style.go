@@ -0,0 +1,105 @@
+package flashcard
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var _ driver.Valuer = &Style{}
+var _ sql.Scanner = &Style{}
+
+// Style is how a textbook's (or subject's) cards look when posted (see
+// notify.Embed). Everything is optional.
+type Style struct {
+	// Hex (ex: #2ECC71) or decimal colour of the embed.
+	Color  string `json:"color,omitempty" yaml:"color"`
+	Author string `json:"author,omitempty" yaml:"author"`
+	// URL of an icon shown next to Author.
+	Icon string `json:"icon,omitempty" yaml:"icon"`
+	// URL of an image shown for cards without a thumbnail.
+	Thumbnail string `json:"thumbnail,omitempty" yaml:"thumbnail"`
+}
+
+// inherit fills in anything style leaves to fallback (ex: its subject's).
+func (style Style) inherit(fallback Style) Style {
+	for _, setting := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&style.Color, fallback.Color},
+		{&style.Author, fallback.Author},
+		{&style.Icon, fallback.Icon},
+		{&style.Thumbnail, fallback.Thumbnail},
+	} {
+		if *setting.value == "" {
+			*setting.value = setting.fallback
+		}
+	}
+	return style
+}
+
+// check reports problems with style, by field.
+func (style Style) check(report func(field string, format string, args ...any)) {
+	if _, err := ParseColor(style.Color); err != nil {
+		report("color", "%v", err)
+	}
+	for _, link := range []struct {
+		field string
+		value string
+	}{
+		{"icon", style.Icon},
+		{"thumbnail", style.Thumbnail},
+	} {
+		if link.value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(link.value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			report(link.field, "%q is not an http(s) URL", link.value)
+		}
+	}
+}
+
+// ParseColor parses a hex (ex: #5865F2 or 0x5865F2) or decimal colour. Empty
+// is no colour.
+func ParseColor(color string) (int, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return 0, nil
+	}
+	number := color
+	if hex, ok := strings.CutPrefix(color, "#"); ok {
+		number = "0x" + hex
+	}
+	value, err := strconv.ParseInt(number, 0, 32)
+	if err != nil || value < 0 || value > 0xFFFFFF {
+		return 0, fmt.Errorf("invalid color %q", color)
+	}
+	return int(value), nil
+}
+
+func (style Style) Value() (driver.Value, error) {
+	return json.Marshal(style)
+}
+
+func (style *Style) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for Style: %T", value)
+	}
+
+	return json.Unmarshal(bytes, style)
+}
@@ -0,0 +1,120 @@
+package flashcard
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var markdownLink = regexp.MustCompile(`^\[(.*?)\]\((.*)\)$`)
+
+// ParseLink splits a markdown link like Origin into its text and URL. ok is
+// false if markdown is not a single link.
+func ParseLink(markdown string) (text string, url string, ok bool) {
+	match := markdownLink.FindStringSubmatch(strings.TrimSpace(markdown))
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+var lineBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</(div|p|li)>`)
+
+// StripHTML converts an HTML snippet (ex: an Anki field) to plain text.
+func StripHTML(snippet string) string {
+	snippet = lineBreaks.ReplaceAllString(snippet, "\n")
+	var builder strings.Builder
+	inTag := false
+	for _, r := range snippet {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			builder.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(html.UnescapeString(builder.String()))
+}
+
+// abbreviations end in a period without ending the sentence.
+var abbreviations = []string{"e.g", "i.e", "etc", "vs", "ca", "approx", "Dr", "Mr", "Mrs", "Ms", "St", "No"}
+
+// SentenceEnds returns the byte offset just past the end of every sentence in
+// text (ex: after "is green." in "Grass is green. It grows."), including the
+// last even if it has no punctuation. Line breaks also end sentences, so lists
+// stay whole.
+func SentenceEnds(text string) []int {
+	var ends []int
+	add := func(end int) {
+		start := 0
+		if len(ends) > 0 {
+			start = ends[len(ends)-1]
+		}
+		// Skip blank lines and line breaks right after punctuation.
+		if strings.TrimSpace(text[start:end]) != "" {
+			ends = append(ends, end)
+		}
+	}
+	runes := []rune(text)
+	offset := 0
+	for i, r := range runes {
+		offset += utf8.RuneLen(r)
+		if r == '\n' {
+			add(offset - 1)
+			continue
+		}
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		// Closing quotes and brackets belong to the sentence.
+		end, j := offset, i+1
+		for j < len(runes) && strings.ContainsRune(`"')]”’»`, runes[j]) {
+			end += utf8.RuneLen(runes[j])
+			j++
+		}
+		if j < len(runes) && !unicode.IsSpace(runes[j]) {
+			continue
+		}
+		// The next sentence has to start with a capital or number (ex: not
+		// "ca. 1900" or "e.g. this").
+		k := j
+		for k < len(runes) && unicode.IsSpace(runes[k]) {
+			k++
+		}
+		if k < len(runes) && !unicode.IsUpper(runes[k]) && !unicode.IsNumber(runes[k]) {
+			continue
+		}
+		word := string(runes[:i])
+		word = word[strings.LastIndexFunc(word, unicode.IsSpace)+1:]
+		if r == '.' && (slices.Contains(abbreviations, strings.Trim(word, "(")) || isInitial(word)) {
+			continue
+		}
+		add(end)
+	}
+	add(len(strings.TrimRightFunc(text, unicode.IsSpace)))
+	return ends
+}
+
+// isInitial reports whether word is a single letter or a one or two digit
+// number, which are usually initials (ex: J. Smith) or list items (ex: 1.
+// Green) rather than the end of a sentence.
+func isInitial(word string) bool {
+	if utf8.RuneCountInString(word) == 1 {
+		return true
+	}
+	return len(word) == 2 && strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+// FirstSentences returns up to n sentences of text (see SentenceEnds).
+func FirstSentences(text string, n int) string {
+	ends := SentenceEnds(text)
+	if n <= 0 || n >= len(ends) {
+		return text
+	}
+	return strings.TrimSpace(text[:ends[n-1]])
+}
@@ -0,0 +1,102 @@
+package flashcard
+
+import (
+	"testing"
+)
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Markdown string
+		Text     string
+		URL      string
+		OK       bool
+	}{
+		{Name: "link", Markdown: "[wikipedia](https://en.wikipedia.org/wiki/Cell)", Text: "wikipedia", URL: "https://en.wikipedia.org/wiki/Cell", OK: true},
+		{Name: "parentheses in url", Markdown: "[wikipedia](https://en.wikipedia.org/wiki/Mercury_(planet))", Text: "wikipedia", URL: "https://en.wikipedia.org/wiki/Mercury_(planet)", OK: true},
+		{Name: "surrounding space", Markdown: " [a](b)\n", Text: "a", URL: "b", OK: true},
+		{Name: "plain text", Markdown: "Campbell Biology"},
+		{Name: "text around link", Markdown: "see [a](b)"},
+		{Name: "empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			text, url, ok := ParseLink(test.Markdown)
+			if text != test.Text || url != test.URL || ok != test.OK {
+				t.Errorf("got %q, %q, %v, want %q, %q, %v", text, url, ok, test.Text, test.URL, test.OK)
+			}
+		})
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Snippet  string
+		Expected string
+	}{
+		{Name: "plain", Snippet: "Cells", Expected: "Cells"},
+		{Name: "tags", Snippet: "<b>Cell</b> <i>wall</i>", Expected: "Cell wall"},
+		{Name: "line breaks", Snippet: "one<br>two<BR /><div>three</div><p>four</p>five", Expected: "one\ntwo\nthree\nfour\nfive"},
+		{Name: "entities", Snippet: "&lt;cell&gt; &amp; wall&nbsp;", Expected: "<cell> & wall"},
+		{Name: "attributes", Snippet: `<a href="https://example.com">diagram</a>`, Expected: "diagram"},
+		{Name: "trims", Snippet: "  <p>cell</p>  ", Expected: "cell"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := StripHTML(test.Snippet); got != test.Expected {
+				t.Errorf("got %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestFirstSentences(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Text     string
+		N        int
+		Expected string
+	}{
+		{Name: "one", Text: "Grass is green. It grows.", N: 1, Expected: "Grass is green."},
+		{Name: "all", Text: "Grass is green. It grows.", N: 0, Expected: "Grass is green. It grows."},
+		{Name: "more than there are", Text: "Grass is green. It grows.", N: 5, Expected: "Grass is green. It grows."},
+		{Name: "no punctuation", Text: "Grass is green", N: 1, Expected: "Grass is green"},
+		{Name: "question and exclamation", Text: "Is grass green? Yes! It is.", N: 2, Expected: "Is grass green? Yes!"},
+		{Name: "closing quote", Text: `He said "grass is green." It is.`, N: 1, Expected: `He said "grass is green."`},
+		{Name: "abbreviation", Text: "Plants, e.g. grass, are green. They grow.", N: 1, Expected: "Plants, e.g. grass, are green."},
+		{Name: "lowercase next word", Text: "Built ca. 1900 or so. Still standing.", N: 1, Expected: "Built ca. 1900 or so."},
+		{Name: "initial", Text: "Described by J. Smith in 1900. Later renamed.", N: 1, Expected: "Described by J. Smith in 1900."},
+		{Name: "decimal", Text: "Pi is about 3.14 in value. It is irrational.", N: 1, Expected: "Pi is about 3.14 in value."},
+		{Name: "line breaks", Text: "Parts:\n- wall\n- membrane", N: 2, Expected: "Parts:\n- wall"},
+		{Name: "blank lines", Text: "First.\n\nSecond.", N: 1, Expected: "First."},
+		{Name: "multibyte", Text: "Çà et là. Ensuite.", N: 1, Expected: "Çà et là."},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := FirstSentences(test.Text, test.N); got != test.Expected {
+				t.Errorf("got %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestSentenceEnds(t *testing.T) {
+	text := "Grass is green. It grows.\n\nFast"
+	ends := SentenceEnds(text)
+	var sentences []string
+	start := 0
+	for _, end := range ends {
+		sentences = append(sentences, text[start:end])
+		start = end
+	}
+	expected := []string{"Grass is green.", " It grows.", "\n\nFast"}
+	if len(sentences) != len(expected) {
+		t.Fatalf("got %q, want %q", sentences, expected)
+	}
+	for i := range expected {
+		if sentences[i] != expected[i] {
+			t.Errorf("sentence %d: got %q, want %q", i, sentences[i], expected[i])
+		}
+	}
+}
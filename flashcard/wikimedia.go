@@ -0,0 +1,111 @@
+package flashcard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxRetries is how many times a rate limited (429) request is retried.
+const MaxRetries = 3
+
+// WikimediaClient makes requests to Wikimedia sites (Wikipedia, Wiktionary)
+// following their API etiquette: a User-Agent with contact information and
+// spacing requests out.
+type WikimediaClient struct {
+	Contact string
+	// Minimum time between requests. Zero disables rate limiting.
+	Interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func Get[T any](ctx context.Context, client *WikimediaClient, url string) (T, error) {
+	var parsed T
+	response, err := client.Do(ctx, "GET", url)
+	if err != nil {
+		return parsed, err
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(response.Body).Decode(&parsed); err != nil {
+		return parsed, fmt.Errorf("reading body: %w", err)
+	}
+	return parsed, nil
+}
+
+// Do performs a request, retrying if rate limited. Responses outside of 2XX
+// are errors.
+func (client *WikimediaClient) Do(ctx context.Context, method string, url string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := client.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		request.Header.Set("User-Agent", fmt.Sprintf(FUserAgent, client.Contact))
+		slog.Debug("performing request", "method", method, "url", url, "headers", request.Header)
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return nil, fmt.Errorf("making response to %s: %w", url, err)
+		}
+		if response.StatusCode == http.StatusTooManyRequests && attempt < MaxRetries {
+			response.Body.Close()
+			client.backoff(response.Header.Get("Retry-After"), attempt)
+			slog.Warn("rate limited", "url", url, "attempt", attempt+1)
+			continue
+		}
+		if response.StatusCode >= 300 || response.StatusCode < 200 {
+			response.Body.Close()
+			return nil, fmt.Errorf("requesting %s: got %s", url, response.Status)
+		}
+		return response, nil
+	}
+}
+
+// wait blocks until the next request is allowed.
+func (client *WikimediaClient) wait(ctx context.Context) error {
+	client.mu.Lock()
+	now := time.Now()
+	start := later(now, client.next)
+	client.next = start.Add(client.Interval)
+	client.mu.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+// backoff delays the next request by Retry-After seconds, or exponentially if
+// it is missing.
+func (client *WikimediaClient) backoff(retryAfter string, attempt int) {
+	delay := time.Second << attempt
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.next = later(client.next, time.Now().Add(delay))
+}
+
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
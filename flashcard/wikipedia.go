@@ -2,29 +2,136 @@ package flashcard
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 )
 
 const (
-	FWikipediaSummaryLink  = "https://en.wikipedia.org/api/rest_v1/page/summary/%s"
-	FWikipediaPageMarkdown = "[wikipedia](https://en.wikipedia.org/wiki/%s)"
-	FUserAgent             = "Flashcard_Bot/0.1 (%s) github.com/ohhfishal/fishy/0.1"
+	// Formatted with the language then the article.
+	FWikipediaSummaryLink   = "https://%s.wikipedia.org/api/rest_v1/page/summary/%s"
+	FWikipediaPageMarkdown  = "[wikipedia](https://%s.wikipedia.org/wiki/%s)"
+	FWikipediaLangLinksLink = "https://%s.wikipedia.org/w/api.php?action=query&prop=langlinks&format=json&formatversion=2&titles=%s&lllang=%s"
+	FWikipediaLinksLink     = "https://%s.wikipedia.org/w/api.php?action=query&prop=links&format=json&formatversion=2&plnamespace=0&pllimit=max&titles=%s"
+	FWikipediaRedirectsLink = "https://%s.wikipedia.org/w/api.php?action=query&prop=redirects&format=json&formatversion=2&rdnamespace=0&rdlimit=max&titles=%s"
+	FUserAgent              = "Flashcard_Bot/0.1 (%s) github.com/ohhfishal/fishy/0.1"
+	// DefaultLanguage is used when neither the article nor textbook set one.
+	DefaultLanguage = "en"
 )
 
+// Summary types (see WikipediaSummaryResponse.Type).
+const (
+	SummaryStandard       = "standard"
+	SummaryDisambiguation = "disambiguation"
+	SummaryNoExtract      = "no-extract"
+	SummaryMainPage       = "mainpage"
+)
+
+// MaxCandidates caps the articles listed for a disambiguation page.
+const MaxCandidates = 20
+
+var ErrNoExtract = errors.New("article has no summary")
+
 type WikipediaSummaryResponse struct {
-	Language  string `json:"lang"`
-	Title     string `json:"title"`
+	Type     string `json:"type"`
+	Language string `json:"lang"`
+	Title    string `json:"title"`
+	// Titles after following redirects.
+	Titles struct {
+		// As used in URLs (ex: Mercury_(planet)).
+		Canonical  string `json:"canonical"`
+		Normalized string `json:"normalized"`
+	} `json:"titles"`
 	Extract   string `json:"extract"`
 	Thumbnail Image  `json:"thumbnail"`
 }
 
+// WikipediaLinksResponse lists the articles a page links to.
+type WikipediaLinksResponse struct {
+	Query struct {
+		Pages []struct {
+			Links []struct {
+				Title string `json:"title"`
+			} `json:"links"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// WikipediaRedirectsResponse lists the titles redirecting to a page (ex:
+// Carbon assimilation to Photosynthesis).
+type WikipediaRedirectsResponse struct {
+	Query struct {
+		Pages []struct {
+			Redirects []struct {
+				Title string `json:"title"`
+			} `json:"redirects"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// DisambiguationError is returned when an article is a disambiguation page
+// instead of the article meant.
+type DisambiguationError struct {
+	Language string
+	Article  string
+	// Articles the page links to, to pick from.
+	Candidates []string
+}
+
+func (err *DisambiguationError) Error() string {
+	return fmt.Sprintf("%s:%s is a disambiguation page, pick one of: %s",
+		err.Language, err.Article, strings.Join(err.Candidates, ", "))
+}
+
+// WikipediaLangLinksResponse lists the same article in other languages, as
+// linked on Wikidata.
+type WikipediaLangLinksResponse struct {
+	Query struct {
+		Pages []struct {
+			Title     string `json:"title"`
+			LangLinks []struct {
+				Language string `json:"lang"`
+				Title    string `json:"title"`
+			} `json:"langlinks"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
 type WikipediaClient struct {
-	Contact string
+	Client *WikimediaClient
+	// Look up the titles redirecting to each article so they are redacted
+	// too.
+	Redirects bool
+	// Sentences of each summary to keep. 0 keeps them all.
+	Sentences int
+}
+
+func init() {
+	RegisterSource("wikipedia", func(args *FlashcardsArgs, client *WikimediaClient) Source {
+		if args.Wikipedia.Disable {
+			return nil
+		}
+		return &WikipediaClient{
+			Client:    client,
+			Redirects: args.Redact.Enabled(),
+			Sentences: args.Wikipedia.Sentences,
+		}
+	})
+}
+
+func (client *WikipediaClient) Name() string {
+	return "wikipedia"
+}
+
+func (client *WikipediaClient) Supports(term Term) bool {
+	return len(term.Wikipedia) > 0
+}
+
+func (client *WikipediaClient) Generate(ctx context.Context, term Term) ([]Flashcard, error) {
+	return client.CreateFlashcards(ctx, term)
 }
 
 func (client *WikipediaClient) CreateFlashcards(ctx context.Context, term Term) ([]Flashcard, error) {
@@ -35,70 +142,166 @@ func (client *WikipediaClient) CreateFlashcards(ctx context.Context, term Term)
 	var cards []Flashcard
 	var errs error
 	for _, article := range term.Wikipedia {
-		card, err := client.CreateFlashcard(ctx, article, term.Name)
+		language, title := SplitLanguage(article, term.Language)
+		card, err := client.CreateFlashcard(ctx, language, title, term.Name)
+		errs = errors.Join(errs, err)
+		if card == nil {
+			continue
+		}
+		cards = append(cards, *card)
+
+		if term.Bilingual == "" || term.Bilingual == language {
+			continue
+		}
+		bilingual, err := client.CreateBilingualFlashcard(ctx, *card, language, title, term.Bilingual)
 		errs = errors.Join(errs, err)
-		if card != nil {
-			cards = append(cards, *card)
+		if bilingual != nil {
+			cards = append(cards, *bilingual)
 		}
 	}
 	return cards, errs
 }
 
-func (client *WikipediaClient) CreateFlashcard(ctx context.Context, article string, header string) (*Flashcard, error) {
+func (client *WikipediaClient) CreateFlashcard(ctx context.Context, language string, article string, header string) (*Flashcard, error) {
 	var description string
 	var thumbnail Image
+	var aliases []string
 	if strings.Contains(article, "#") {
 		// TODO: Parse the actual html page
 		return nil, fmt.Errorf("not implemented: headings: %s", article)
 	} else {
-		summary, err := Get[WikipediaSummaryResponse](client, fmt.Sprintf(FWikipediaSummaryLink, article), nil)
+		summary, err := client.summary(ctx, language, article)
 		if err != nil {
 			return nil, err
 		}
-		description = summary.Extract
+		switch summary.Type {
+		case SummaryDisambiguation:
+			return nil, client.disambiguation(ctx, language, article)
+		case SummaryNoExtract, SummaryMainPage:
+			return nil, fmt.Errorf("%s:%s: %w", language, article, ErrNoExtract)
+		}
+		if summary.Extract == "" {
+			return nil, fmt.Errorf("%s:%s: %w", language, article, ErrNoExtract)
+		}
+		description = FirstSentences(summary.Extract, client.Sentences)
 		thumbnail = summary.Thumbnail
+		if summary.Language != "" {
+			language = summary.Language
+		}
+		// Redirects are followed, so link to where they went.
+		if summary.Titles.Canonical != "" && summary.Titles.Canonical != strings.ReplaceAll(article, " ", "_") {
+			slog.Debug("followed redirect", "language", language, "article", article, "canonical", summary.Titles.Canonical)
+			article = summary.Titles.Canonical
+		}
+		aliases = append(aliases, summary.Titles.Normalized)
+		if client.Redirects {
+			aliases = append(aliases, client.redirects(ctx, language, article)...)
+		}
 	}
 	return &Flashcard{
 		Header:      header,
 		Description: description,
-		Origin:      fmt.Sprintf(FWikipediaPageMarkdown, article),
+		Origin:      fmt.Sprintf(FWikipediaPageMarkdown, language, article),
+		Language:    language,
 		Thumbnail:   thumbnail,
+		aliases:     aliases,
 	}, nil
 }
 
-func Get[T any](client *WikipediaClient, url string, body any) (T, error) {
-	return Do[T](client, "GET", url, body)
-}
-func Do[T any](client *WikipediaClient, method string, url string, body any) (T, error) {
-	var parsed T
-	response, err := client.Do(method, url, body)
+// CreateBilingualFlashcard pairs card, made from article, with the same
+// article in another language. The answer is in the other language.
+func (client *WikipediaClient) CreateBilingualFlashcard(ctx context.Context, card Flashcard, language string, article string, other string) (*Flashcard, error) {
+	links, err := Get[WikipediaLangLinksResponse](ctx, client.Client, fmt.Sprintf(FWikipediaLangLinksLink, language, url.QueryEscape(article), url.QueryEscape(other)))
 	if err != nil {
-		return parsed, fmt.Errorf("requesting wikipedia: %w", err)
+		return nil, fmt.Errorf("%s:%s: finding %s article: %w", language, article, other, err)
+	}
+	var title string
+	for _, page := range links.Query.Pages {
+		for _, link := range page.LangLinks {
+			if link.Language == other {
+				title = link.Title
+			}
+		}
+	}
+	if title == "" {
+		return nil, fmt.Errorf("%s:%s: no %s article", language, article, other)
 	}
-	defer response.Body.Close()
 
-	if err := json.NewDecoder(response.Body).Decode(&parsed); err != nil {
-		return parsed, fmt.Errorf("reading body: %w", err)
+	summary, err := client.summary(ctx, other, title)
+	if err != nil {
+		return nil, err
+	} else if summary.Type != SummaryStandard || summary.Extract == "" {
+		return nil, fmt.Errorf("%s:%s: %w", other, title, ErrNoExtract)
 	}
-	return parsed, nil
+	card.Description = FirstSentences(summary.Extract, client.Sentences)
+	// The other title is the answer when reversed, so it is redacted too.
+	card.aliases = append(slices.Clone(card.aliases), title)
+	card.Origin = fmt.Sprintf(FWikipediaPageMarkdown, other, strings.ReplaceAll(title, " ", "_"))
+	card.Language = language + "/" + other
+	return &card, nil
 }
 
-func (client *WikipediaClient) Do(method string, url string, body any) (*http.Response, error) {
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+// disambiguation lists the candidate articles of a disambiguation page,
+// warning about it so the textbook can be fixed.
+func (client *WikipediaClient) disambiguation(ctx context.Context, language string, article string) error {
+	err := &DisambiguationError{Language: language, Article: article}
+	links, linksErr := Get[WikipediaLinksResponse](ctx, client.Client, fmt.Sprintf(FWikipediaLinksLink, language, url.QueryEscape(article)))
+	if linksErr != nil {
+		slog.Warn("could not list disambiguation candidates", "language", language, "article", article, "err", linksErr)
 	}
-	request.Header.Set("User-Agent", fmt.Sprintf(FUserAgent, client.Contact))
-	slog.Debug("performing get", "url", url, "headers", request.Header)
+	for _, page := range links.Query.Pages {
+		for _, link := range page.Links {
+			if len(err.Candidates) < MaxCandidates {
+				err.Candidates = append(err.Candidates, link.Title)
+			}
+		}
+	}
+	slog.Warn("article is a disambiguation page", "language", language, "article", article, "candidates", err.Candidates)
+	return err
+}
 
-	response, err := http.DefaultClient.Do(request)
+// redirects lists the titles redirecting to article. It only warns on errors
+// since the card is still usable without them.
+func (client *WikipediaClient) redirects(ctx context.Context, language string, article string) []string {
+	response, err := Get[WikipediaRedirectsResponse](ctx, client.Client, fmt.Sprintf(FWikipediaRedirectsLink, language, url.QueryEscape(article)))
 	if err != nil {
-		return nil, fmt.Errorf("making response to %s: %w", url, err)
+		slog.Warn("could not list redirects", "language", language, "article", article, "err", err)
+		return nil
+	}
+	var titles []string
+	for _, page := range response.Query.Pages {
+		for _, redirect := range page.Redirects {
+			titles = append(titles, redirect.Title)
+		}
 	}
-	if response.StatusCode >= 300 || response.StatusCode < 200 {
-		slog.Warn("got", "status", response.Status)
-		// TODO: HANDLE
+	return titles
+}
+
+func (client *WikipediaClient) summary(ctx context.Context, language string, article string) (WikipediaSummaryResponse, error) {
+	return Get[WikipediaSummaryResponse](ctx, client.Client, fmt.Sprintf(FWikipediaSummaryLink, language, url.PathEscape(article)))
+}
+
+// SplitLanguage splits the language prefix off of article (ex: fr:Photosynthèse),
+// using fallback (or DefaultLanguage) if it has none.
+func SplitLanguage(article string, fallback string) (language string, title string) {
+	if prefix, rest, ok := strings.Cut(article, ":"); ok && isLanguageCode(prefix) {
+		return prefix, rest
+	} else if fallback != "" {
+		return fallback, article
+	}
+	return DefaultLanguage, article
+}
+
+// isLanguageCode reports whether prefix looks like a language code (ex: en,
+// es, zh-min-nan) rather than part of a word.
+func isLanguageCode(prefix string) bool {
+	if len(prefix) < 2 || len(prefix) > 12 {
+		return false
+	}
+	for _, r := range prefix {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return false
+		}
 	}
-	// TODO: Slow down if we get a 429 etc
-	return response, nil
+	return true
 }
@@ -0,0 +1,204 @@
+package flashcard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// WiktionaryEdition is the only Wiktionary with a definition API, so every
+// card is in its language.
+const WiktionaryEdition = "en"
+
+const (
+	FWiktionaryDefinitionLink = "https://en.wiktionary.org/api/rest_v1/page/definition/%s"
+	FWiktionaryWikitextLink   = "https://en.wiktionary.org/w/api.php?action=parse&prop=wikitext&format=json&formatversion=2&page=%s"
+	FWiktionaryPageMarkdown   = "[wiktionary](https://en.wiktionary.org/wiki/%s#%s)"
+)
+
+// Limits on what goes on a card so the answer stays readable.
+const (
+	MaxWiktionaryDefinitions = 3
+	MaxWiktionaryExamples    = 1
+)
+
+// WiktionaryDefinitionResponse is keyed by language code (ex: es).
+type WiktionaryDefinitionResponse map[string][]WiktionaryUsage
+
+type WiktionaryUsage struct {
+	PartOfSpeech string                 `json:"partOfSpeech"`
+	Language     string                 `json:"language"`
+	Definitions  []WiktionaryDefinition `json:"definitions"`
+}
+
+type WiktionaryDefinition struct {
+	// HTML
+	Definition     string   `json:"definition"`
+	Examples       []string `json:"examples"`
+	ParsedExamples []struct {
+		Example     string `json:"example"`
+		Translation string `json:"translation"`
+	} `json:"parsedExamples"`
+}
+
+type WiktionaryWikitextResponse struct {
+	Parse struct {
+		Wikitext string `json:"wikitext"`
+	} `json:"parse"`
+}
+
+// ErrWiktionaryLanguage is returned for terms in textbooks that are not in
+// WiktionaryEdition's language.
+var ErrWiktionaryLanguage = errors.New("wiktionary definitions are only in English")
+
+type WiktionaryClient struct {
+	Client *WikimediaClient
+}
+
+func init() {
+	RegisterSource("wiktionary", func(args *FlashcardsArgs, client *WikimediaClient) Source {
+		if args.Wiktionary.Disable {
+			return nil
+		}
+		return &WiktionaryClient{Client: client}
+	})
+}
+
+func (client *WiktionaryClient) Name() string {
+	return "wiktionary"
+}
+
+func (client *WiktionaryClient) Supports(term Term) bool {
+	return len(term.Wiktionary) > 0
+}
+
+func (client *WiktionaryClient) Generate(ctx context.Context, term Term) ([]Flashcard, error) {
+	return client.CreateFlashcards(ctx, term)
+}
+
+func (client *WiktionaryClient) CreateFlashcards(ctx context.Context, term Term) ([]Flashcard, error) {
+	if len(term.Wiktionary) == 0 {
+		return nil, fmt.Errorf("does not support wiktionary: %v", term)
+	}
+
+	if term.Language != "" && term.Language != WiktionaryEdition {
+		return nil, fmt.Errorf("%w: got language %s (prefix foreign words instead, ex: es:gato)", ErrWiktionaryLanguage, term.Language)
+	}
+
+	var cards []Flashcard
+	var errs error
+	for _, entry := range term.Wiktionary {
+		language, word := SplitLanguage(entry, WiktionaryEdition)
+		card, err := client.CreateFlashcard(ctx, language, word, term.Name)
+		errs = errors.Join(errs, err)
+		if card != nil {
+			cards = append(cards, *card)
+		}
+	}
+	return cards, errs
+}
+
+// CreateFlashcard makes a card for word from the section for language (ex: es
+// for Spanish). Definitions are always from WiktionaryEdition.
+func (client *WiktionaryClient) CreateFlashcard(ctx context.Context, language string, word string, header string) (*Flashcard, error) {
+	entry := language + ":" + word
+	title := strings.ReplaceAll(strings.TrimSpace(word), " ", "_")
+
+	definitions, err := Get[WiktionaryDefinitionResponse](ctx, client.Client, fmt.Sprintf(FWiktionaryDefinitionLink, url.PathEscape(title)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", entry, err)
+	}
+	usages := definitions[language]
+	if len(usages) == 0 {
+		return nil, fmt.Errorf("%s: no %s entry", entry, language)
+	}
+
+	// Pronunciation is nice to have, so a failure here is not fatal.
+	var ipa string
+	wikitext, err := Get[WiktionaryWikitextResponse](ctx, client.Client, fmt.Sprintf(FWiktionaryWikitextLink, url.QueryEscape(title)))
+	if err == nil {
+		ipa = ParseIPA(wikitext.Parse.Wikitext, usages[0].Language)
+	}
+
+	return &Flashcard{
+		Header:      header,
+		Description: WiktionaryDescription(ipa, usages),
+		Origin:      fmt.Sprintf(FWiktionaryPageMarkdown, title, strings.ReplaceAll(usages[0].Language, " ", "_")),
+		Language:    language,
+	}, nil
+}
+
+// WiktionaryDescription formats the pronunciation and each part of speech
+// with its first few definitions and examples.
+func WiktionaryDescription(ipa string, usages []WiktionaryUsage) string {
+	var builder strings.Builder
+	if ipa != "" {
+		fmt.Fprintf(&builder, "%s\n", ipa)
+	}
+	for _, usage := range usages {
+		var definitions []string
+		var examples []string
+		for _, definition := range usage.Definitions {
+			text := StripHTML(definition.Definition)
+			if text == "" || len(definitions) == MaxWiktionaryDefinitions {
+				continue
+			}
+			definitions = append(definitions, text)
+			for _, parsed := range definition.ParsedExamples {
+				if len(examples) < MaxWiktionaryExamples {
+					example := StripHTML(parsed.Example)
+					if translation := StripHTML(parsed.Translation); translation != "" {
+						example = fmt.Sprintf("%s (%s)", example, translation)
+					}
+					examples = append(examples, example)
+				}
+			}
+			for _, example := range definition.Examples {
+				if len(examples) < MaxWiktionaryExamples {
+					examples = append(examples, StripHTML(example))
+				}
+			}
+		}
+		if len(definitions) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&builder, "\n*%s*\n", strings.ToLower(usage.PartOfSpeech))
+		for i, definition := range definitions {
+			fmt.Fprintf(&builder, "%d. %s\n", i+1, definition)
+		}
+		for _, example := range examples {
+			fmt.Fprintf(&builder, "> %s\n", example)
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+var (
+	ipaTemplate = regexp.MustCompile(`\{\{IPA\|[^|}]+\|([^|}]+)`)
+	// Level 2 headings start the section for each language (ex: ==Spanish==).
+	languageHeading = regexp.MustCompile(`(?m)^==([^=].*?)==\s*$`)
+)
+
+// ParseIPA returns the first IPA pronunciation in the language section of a
+// page's wikitext (ex: {{IPA|es|/ˈɡato/}}).
+func ParseIPA(wikitext string, language string) string {
+	headings := languageHeading.FindAllStringSubmatchIndex(wikitext, -1)
+	for i, heading := range headings {
+		if strings.TrimSpace(wikitext[heading[2]:heading[3]]) != language {
+			continue
+		}
+		section := wikitext[heading[1]:]
+		if i+1 < len(headings) {
+			section = wikitext[heading[1]:headings[i+1][0]]
+		}
+		if match := ipaTemplate.FindStringSubmatch(section); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+	return ""
+}
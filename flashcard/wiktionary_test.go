@@ -0,0 +1,15 @@
+package flashcard
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWiktionaryLanguage(t *testing.T) {
+	client := &WiktionaryClient{Client: &WikimediaClient{}}
+	term := Term{Name: "cat", Language: "es", Wiktionary: []string{"gato"}}
+	if _, err := client.CreateFlashcards(context.Background(), term); !errors.Is(err, ErrWiktionaryLanguage) {
+		t.Errorf("got %v, want %v", err, ErrWiktionaryLanguage)
+	}
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrUnsupportedPackage = errors.New("only packages exported with \"Support older Anki versions\" are supported")
+
+// Collections in an Anki package, most preferred first. Packages with a
+// collection.anki21 also include a placeholder collection.anki2.
+var ankiCollections = []string{"collection.anki21", "collection.anki2"}
+
+type AnkiCMD struct {
+	File     string  `arg:"" type:"existingfile" help:"Anki package (.apkg or .colpkg) to import."`
+	Database string  `arg:"" default:"fishy.db" help:"SQLite connection string."`
+	Mapping  Mapping `embed:"" group:"Field Mapping"`
+}
+
+func (config *AnkiCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	result, err := ReadAnki(ctx, config.File, config.Mapping)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", config.File, err)
+	}
+	return Load(ctx, config.Database, result, logger)
+}
+
+// ReadAnki maps the notes of an Anki package to cards. Mapping columns refer
+// to the fields of each note type. Unless Mapping.Tags is set, note tags are
+// used.
+func ReadAnki(ctx context.Context, path string, mapping Mapping) (Result, error) {
+	var result Result
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return result, err
+	}
+	defer archive.Close()
+
+	dir, err := os.MkdirTemp("", "fishy-anki-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(dir)
+
+	collection, err := extractCollection(&archive.Reader, dir)
+	if err != nil {
+		return result, err
+	}
+
+	db, err := sql.Open("sqlite", collection)
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+
+	models, err := ankiModels(ctx, db)
+	if err != nil {
+		return result, fmt.Errorf("reading note types: %w", err)
+	}
+	notes, err := ankiNotes(ctx, db)
+	if err != nil {
+		return result, fmt.Errorf("reading notes: %w", err)
+	}
+
+	source := filepath.Base(path)
+	for _, id := range slices.Sorted(maps.Keys(notes)) {
+		fields, ok := models[id]
+		if !ok {
+			for _, note := range notes[id] {
+				result.skip(note.Number, fmt.Sprintf("unknown note type %d", id))
+			}
+			continue
+		}
+		mapped, err := mapping.Map(notes[id], fields, source)
+		if err != nil {
+			return result, fmt.Errorf("note type %d: %w", id, err)
+		}
+		result.Merge(mapped)
+	}
+	return result, nil
+}
+
+func extractCollection(archive *zip.Reader, dir string) (string, error) {
+	for _, name := range ankiCollections {
+		file, err := archive.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		path := filepath.Join(dir, name)
+		out, err := os.Create(path)
+		if err != nil {
+			return "", err
+		}
+		defer out.Close()
+		if _, err := io.Copy(out, file); err != nil {
+			return "", fmt.Errorf("extracting %s: %w", name, err)
+		}
+		return path, out.Close()
+	}
+	return "", ErrUnsupportedPackage
+}
+
+// ankiModels returns the field names of each note type.
+func ankiModels(ctx context.Context, db *sql.DB) (map[int64][]string, error) {
+	var raw string
+	if err := db.QueryRowContext(ctx, "SELECT models FROM col").Scan(&raw); err != nil {
+		return nil, err
+	}
+
+	models := map[int64][]string{}
+	// Newer collections keep note types in their own tables.
+	if strings.TrimSpace(raw) == "" || strings.TrimSpace(raw) == "{}" {
+		rows, err := db.QueryContext(ctx, "SELECT ntid, name FROM fields ORDER BY ntid, ord")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return nil, err
+			}
+			models[id] = append(models[id], name)
+		}
+		return models, rows.Err()
+	}
+
+	var parsed map[string]struct {
+		ID     int64 `json:"id"`
+		Fields []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, err
+	}
+	for _, model := range parsed {
+		fields := make([]string, len(model.Fields))
+		for _, field := range model.Fields {
+			if field.Ord >= 0 && field.Ord < len(fields) {
+				fields[field.Ord] = field.Name
+			}
+		}
+		models[model.ID] = fields
+	}
+	return models, nil
+}
+
+// ankiNotes returns notes as rows grouped by note type.
+func ankiNotes(ctx context.Context, db *sql.DB) (map[int64][]Row, error) {
+	rows, err := db.QueryContext(ctx, "SELECT mid, tags, flds FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := map[int64][]Row{}
+	for number := 1; rows.Next(); number++ {
+		var model int64
+		var tags, fields string
+		if err := rows.Scan(&model, &tags, &fields); err != nil {
+			return nil, err
+		}
+		notes[model] = append(notes[model], Row{
+			Number: number,
+			Values: strings.Split(fields, "\x1f"),
+			Tags:   tags,
+		})
+	}
+	return notes, rows.Err()
+}
@@ -0,0 +1,72 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ohhfishal/fishy/flashcard"
+	_ "modernc.org/sqlite"
+)
+
+// TestReadAnki imports testdata/biology.apkg, written by export anki with a
+// duplicate note and one without a front.
+func TestReadAnki(t *testing.T) {
+	t.Run("notes", func(t *testing.T) {
+		result, err := ReadAnki(context.Background(), "testdata/biology.apkg", Mapping{Front: "Header", Back: "Description"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []flashcard.Flashcard{
+			{Header: "Photosynthesis", Description: "Plants convert light into energy.", Origin: "biology.apkg", ClassContext: "Biology Chapter:_10"},
+			{Header: "Cellular respiration", Description: "Cells release energy from glucose.", Origin: "biology.apkg", ClassContext: "Biology Chapter:_9"},
+			{Header: "Photosynthesis", Description: "Happens in chloroplasts.", Origin: "biology.apkg", ClassContext: "Biology Chapter:_10"},
+		}
+		if !slices.EqualFunc(result.Cards, want, sameCard) {
+			t.Errorf("got cards %+v, want %+v", result.Cards, want)
+		}
+		if want := []int{1, 2, 3}; !slices.Equal(result.Rows, want) {
+			t.Errorf("got rows %v, want %v", result.Rows, want)
+		}
+		if want := []Skipped{{Row: 4, Reason: "empty front"}}; !slices.Equal(result.Skipped, want) {
+			t.Errorf("got skipped %v, want %v", result.Skipped, want)
+		}
+	})
+
+	t.Run("tags field", func(t *testing.T) {
+		result, err := ReadAnki(context.Background(), "testdata/biology.apkg", Mapping{Front: "0", Back: "1", Tags: "Source"})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(result.Cards) == 0 || result.Cards[0].ClassContext != "Campbell Biology" {
+			t.Errorf("got cards %+v, want tags from the Source field", result.Cards)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		if _, err := ReadAnki(context.Background(), "testdata/biology.apkg", Mapping{Front: "Question", Back: "1"}); err == nil {
+			t.Error("expected an error for an unknown field")
+		}
+	})
+
+	t.Run("newer package", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "new.apkg")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive := zip.NewWriter(file)
+		if _, err := archive.Create("collection.anki21b"); err != nil {
+			t.Fatal(err)
+		}
+		archive.Close()
+		file.Close()
+
+		if _, err := ReadAnki(context.Background(), path, Mapping{Front: "0", Back: "1"}); !errors.Is(err, ErrUnsupportedPackage) {
+			t.Errorf("got %v, want %v", err, ErrUnsupportedPackage)
+		}
+	})
+}
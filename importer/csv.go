@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type CSVCMD struct {
+	File      string  `arg:"" type:"existingfile" help:"CSV or TSV file to import."`
+	Database  string  `arg:"" default:"fishy.db" help:"SQLite connection string."`
+	Mapping   Mapping `embed:"" group:"Column Mapping"`
+	Delimiter string  `help:"Field delimiter. Defaults to a tab for .tsv files and a comma otherwise."`
+	NoHeader  bool    `help:"The first row is data instead of column names."`
+}
+
+func (config *CSVCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	file, err := os.Open(config.File)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", config.File, err)
+	}
+	defer file.Close()
+
+	rows, columns, err := ReadCSV(file, config.delimiter(), !config.NoHeader)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", config.File, err)
+	}
+	result, err := config.Mapping.Map(rows, columns, filepath.Base(config.File))
+	if err != nil {
+		return err
+	}
+	return Load(ctx, config.Database, result, logger)
+}
+
+func (config *CSVCMD) delimiter() rune {
+	switch {
+	case config.Delimiter == `\t`:
+		return '\t'
+	case config.Delimiter != "":
+		return []rune(config.Delimiter)[0]
+	case strings.EqualFold(filepath.Ext(config.File), ".tsv"):
+		return '\t'
+	default:
+		return ','
+	}
+}
+
+// ReadCSV reads every record. If header is set the first record names the
+// columns.
+func ReadCSV(reader io.Reader, delimiter rune, header bool) ([]Row, []string, error) {
+	records := csv.NewReader(reader)
+	records.Comma = delimiter
+	records.FieldsPerRecord = -1
+	records.LazyQuotes = true
+
+	var rows []Row
+	var columns []string
+	for number := 1; ; number++ {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if header && number == 1 {
+			columns = record
+			continue
+		}
+		rows = append(rows, Row{Number: number, Values: record})
+	}
+	return rows, columns, nil
+}
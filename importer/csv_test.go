@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     string
+		Delimiter rune
+		Header    bool
+		Columns   []string
+		Rows      []Row
+	}{
+		{
+			Name:    "header",
+			Input:   "front,back\nCell,Unit of life\n",
+			Header:  true,
+			Columns: []string{"front", "back"},
+			Rows:    []Row{{Number: 2, Values: []string{"Cell", "Unit of life"}}},
+		},
+		{
+			Name:  "no header",
+			Input: "Cell,Unit of life\nAtom,Unit of matter\n",
+			Rows: []Row{
+				{Number: 1, Values: []string{"Cell", "Unit of life"}},
+				{Number: 2, Values: []string{"Atom", "Unit of matter"}},
+			},
+		},
+		{
+			Name:  "quoting",
+			Input: "\"Cell, plant\",\"Has a \"\"wall\"\"\nand a vacuole\"\n",
+			Rows:  []Row{{Number: 1, Values: []string{"Cell, plant", "Has a \"wall\"\nand a vacuole"}}},
+		},
+		{
+			Name:  "lazy quotes",
+			Input: "Cell,The \"basic\" unit\n",
+			Rows:  []Row{{Number: 1, Values: []string{"Cell", "The \"basic\" unit"}}},
+		},
+		{
+			Name:      "tsv",
+			Input:     "front\tback\nCell\tUnit, of life\n",
+			Delimiter: '\t',
+			Header:    true,
+			Columns:   []string{"front", "back"},
+			Rows:      []Row{{Number: 2, Values: []string{"Cell", "Unit, of life"}}},
+		},
+		{
+			Name:  "ragged rows",
+			Input: "Cell\nAtom,Unit of matter,Chemistry\n",
+			Rows: []Row{
+				{Number: 1, Values: []string{"Cell"}},
+				{Number: 2, Values: []string{"Atom", "Unit of matter", "Chemistry"}},
+			},
+		},
+		{
+			Name:    "header only",
+			Input:   "front,back\n",
+			Header:  true,
+			Columns: []string{"front", "back"},
+		},
+		{
+			Name:  "unterminated quote",
+			Input: "Cell,\"Unit of life\nAtom,Unit of matter\n",
+			Rows:  []Row{{Number: 1, Values: []string{"Cell", "Unit of life\nAtom,Unit of matter\n"}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delimiter := test.Delimiter
+			if delimiter == 0 {
+				delimiter = ','
+			}
+			rows, columns, err := ReadCSV(strings.NewReader(test.Input), delimiter, test.Header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(columns, test.Columns) {
+				t.Errorf("got columns %q, want %q", columns, test.Columns)
+			}
+			if !slices.EqualFunc(rows, test.Rows, func(a, b Row) bool {
+				return a.Number == b.Number && slices.Equal(a.Values, b.Values) && a.Tags == b.Tags
+			}) {
+				t.Errorf("got rows %q, want %q", rows, test.Rows)
+			}
+		})
+	}
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		File      string
+		Delimiter string
+		Expected  rune
+	}{
+		{File: "cards.csv", Expected: ','},
+		{File: "cards.tsv", Expected: '\t'},
+		{File: "cards.TSV", Expected: '\t'},
+		{File: "cards.txt", Delimiter: ";", Expected: ';'},
+		{File: "cards.csv", Delimiter: `\t`, Expected: '\t'},
+	}
+	for _, test := range tests {
+		config := CSVCMD{File: test.File, Delimiter: test.Delimiter}
+		if got := config.delimiter(); got != test.Expected {
+			t.Errorf("%s with %q: got %q, want %q", test.File, test.Delimiter, got, test.Expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+type CMD struct {
+	Anki AnkiCMD `cmd:"" help:"Import notes from an Anki package (.apkg or .colpkg)."`
+	CSV  CSVCMD  `cmd:"" name:"csv" help:"Import rows from a CSV or TSV file."`
+}
+
+// Mapping picks which columns (or Anki fields) become which part of a card.
+// Columns are given by name or 0-based index.
+type Mapping struct {
+	Front string `default:"0" help:"Column holding the front of the card (Header)."`
+	Back  string `default:"1" help:"Column holding the back of the card (Description)."`
+	Tags  string `help:"Column holding tags (ClassContext)."`
+}
+
+// Row is a single record of an import before it is mapped to a card.
+type Row struct {
+	// Number of the row (or Anki note) in the file, starting at 1.
+	Number int
+	Values []string
+	// Tags to use when Mapping.Tags is unset.
+	Tags string
+}
+
+// Skipped is a row that was not imported.
+type Skipped struct {
+	Row    int
+	Reason string
+}
+
+// Result holds the cards mapped from rows and the rows that were skipped.
+type Result struct {
+	Cards []flashcard.Flashcard
+	// Row number each card came from.
+	Rows    []int
+	Skipped []Skipped
+}
+
+func (result *Result) Merge(other Result) {
+	result.Cards = append(result.Cards, other.Cards...)
+	result.Rows = append(result.Rows, other.Rows...)
+	result.Skipped = append(result.Skipped, other.Skipped...)
+}
+
+func (result *Result) skip(row int, reason string) {
+	result.Skipped = append(result.Skipped, Skipped{Row: row, Reason: reason})
+}
+
+// Map maps rows to cards with origin set to source. columns names the values
+// of each row.
+func (mapping Mapping) Map(rows []Row, columns []string, source string) (Result, error) {
+	var result Result
+	front, err := column(mapping.Front, columns)
+	if err != nil {
+		return result, fmt.Errorf("front: %w", err)
+	}
+	back, err := column(mapping.Back, columns)
+	if err != nil {
+		return result, fmt.Errorf("back: %w", err)
+	}
+	tags := -1
+	if mapping.Tags != "" {
+		if tags, err = column(mapping.Tags, columns); err != nil {
+			return result, fmt.Errorf("tags: %w", err)
+		}
+	}
+
+	for _, row := range rows {
+		get := func(i int) (string, bool) {
+			if i >= len(row.Values) {
+				return "", false
+			}
+			return flashcard.StripHTML(row.Values[i]), true
+		}
+
+		header, ok := get(front)
+		if !ok {
+			result.skip(row.Number, "missing front column")
+			continue
+		} else if header == "" {
+			result.skip(row.Number, "empty front")
+			continue
+		}
+		description, ok := get(back)
+		if !ok {
+			result.skip(row.Number, "missing back column")
+			continue
+		} else if description == "" {
+			result.skip(row.Number, "empty back")
+			continue
+		}
+		context := strings.TrimSpace(row.Tags)
+		if tags >= 0 {
+			context, _ = get(tags)
+		}
+
+		result.Cards = append(result.Cards, flashcard.Flashcard{
+			Header:       header,
+			Description:  description,
+			Origin:       source,
+			ClassContext: context,
+		})
+		result.Rows = append(result.Rows, row.Number)
+	}
+	return result, nil
+}
+
+// column finds name in columns, falling back to treating it as an index.
+func column(name string, columns []string) (int, error) {
+	for i, column := range columns {
+		if strings.EqualFold(strings.TrimSpace(column), name) {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no column %q in %v", name, columns)
+}
+
+// Load inserts the cards of result into the database at path the same way as
+// LoadFlashcards, logging every row that was skipped.
+func Load(ctx context.Context, path string, result Result, logger *slog.Logger) error {
+	db, err := database.Connect(ctx, "sqlite", path)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	duplicates, err := db.InsertFlashcards(ctx, result.Cards)
+	if err != nil {
+		return fmt.Errorf("inserting cards: %w", err)
+	}
+	for _, i := range duplicates {
+		result.skip(result.Rows[i], "duplicate card")
+	}
+
+	slices.SortFunc(result.Skipped, func(a, b Skipped) int {
+		return a.Row - b.Row
+	})
+	for _, skip := range result.Skipped {
+		logger.Warn("skipped row", "row", skip.Row, "reason", skip.Reason)
+	}
+	logger.Info("imported", "cards", len(result.Cards)-len(duplicates), "skipped", len(result.Skipped))
+	return nil
+}
@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+func TestMap(t *testing.T) {
+	columns := []string{"Term", "Definition", "Tags"}
+	rows := []Row{
+		{Number: 2, Values: []string{"Cell", "<b>Unit</b> of life", "Biology"}},
+		{Number: 3, Values: []string{"", "No front", "Biology"}},
+		{Number: 4, Values: []string{"Atom"}},
+		{Number: 5, Values: []string{"Atom", " ", "Chemistry"}},
+		{Number: 6, Values: []string{"Atom", "Unit of matter"}, Tags: " Chemistry "},
+	}
+	tests := []struct {
+		Name    string
+		Mapping Mapping
+		Cards   []flashcard.Flashcard
+		Rows    []int
+		Skipped []Skipped
+		Err     bool
+	}{
+		{
+			Name:    "by name",
+			Mapping: Mapping{Front: "term", Back: "Definition", Tags: "tags"},
+			Cards: []flashcard.Flashcard{
+				{Header: "Cell", Description: "Unit of life", Origin: "cards.csv", ClassContext: "Biology"},
+				{Header: "Atom", Description: "Unit of matter", Origin: "cards.csv"},
+			},
+			Rows: []int{2, 6},
+			Skipped: []Skipped{
+				{Row: 3, Reason: "empty front"},
+				{Row: 4, Reason: "missing back column"},
+				{Row: 5, Reason: "empty back"},
+			},
+		},
+		{
+			Name:    "by index with row tags",
+			Mapping: Mapping{Front: "0", Back: "1"},
+			Cards: []flashcard.Flashcard{
+				{Header: "Cell", Description: "Unit of life", Origin: "cards.csv"},
+				{Header: "Atom", Description: "Unit of matter", Origin: "cards.csv", ClassContext: "Chemistry"},
+			},
+			Rows: []int{2, 6},
+			Skipped: []Skipped{
+				{Row: 3, Reason: "empty front"},
+				{Row: 4, Reason: "missing back column"},
+				{Row: 5, Reason: "empty back"},
+			},
+		},
+		{
+			Name:    "unknown column",
+			Mapping: Mapping{Front: "Question", Back: "1"},
+			Err:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := test.Mapping.Map(rows, columns, "cards.csv")
+			if (err != nil) != test.Err {
+				t.Fatalf("got error %v, want error: %v", err, test.Err)
+			} else if err != nil {
+				return
+			}
+			if !slices.EqualFunc(result.Cards, test.Cards, sameCard) {
+				t.Errorf("got cards %+v, want %+v", result.Cards, test.Cards)
+			}
+			if !slices.Equal(result.Rows, test.Rows) {
+				t.Errorf("got rows %v, want %v", result.Rows, test.Rows)
+			}
+			if !slices.Equal(result.Skipped, test.Skipped) {
+				t.Errorf("got skipped %v, want %v", result.Skipped, test.Skipped)
+			}
+		})
+	}
+}
+
+// TestLoad checks rows already in the database are reported by row number.
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fishy.db")
+	rows, columns, err := ReadCSV(strings.NewReader("front,back\nCell,Unit of life\nAtom,Unit of matter\nCell,Again\n"), ',', true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Mapping{Front: "front", Back: "back"}.Map(rows, columns, "cards.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	ctx := context.Background()
+	if err := Load(ctx, path, result, logger); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`msg="skipped row" row=4 reason="duplicate card"`,
+		`msg=imported cards=2 skipped=1`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("missing %s in logs:\n%s", want, logs.String())
+		}
+	}
+
+	// Importing again skips every row.
+	logs.Reset()
+	if err := Load(ctx, path, result, logger); err != nil {
+		t.Fatal(err)
+	} else if want := `msg=imported cards=0 skipped=3`; !strings.Contains(logs.String(), want) {
+		t.Errorf("missing %s in logs:\n%s", want, logs.String())
+	}
+
+	db, err := database.Connect(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cards, err := db.GetCards(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(cards) != 2 {
+		t.Errorf("got %d cards, want 2", len(cards))
+	}
+}
+
+func sameCard(a, b flashcard.Flashcard) bool {
+	return a.Header == b.Header && a.Description == b.Description && a.Origin == b.Origin && a.ClassContext == b.ClassContext
+}
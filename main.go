@@ -12,7 +12,10 @@ import (
 	"syscall"
 
 	"github.com/alecthomas/kong"
+	"github.com/ohhfishal/fishy/config"
+	"github.com/ohhfishal/fishy/export"
 	"github.com/ohhfishal/fishy/flashcard"
+	"github.com/ohhfishal/fishy/importer"
 	"github.com/ohhfishal/fishy/notify"
 	"github.com/ohhfishal/fishy/serve"
 	konghelp "github.com/ohhfishal/kong-help"
@@ -21,10 +24,16 @@ import (
 var ErrDone = errors.New("program ready to exit")
 
 type Cmd struct {
-	LogConfig LogConfig             `embed:"" group:"Logging Flags:"`
-	Generate  flashcard.GenerateCMD `cmd:"" default:"withargs" help:"Generate all flashcards."`
-	Notify    notify.NotifyCMD      `cmd:"" help:"Use generated flashcards to notify."`
-	Serve     serve.CMD             `cmd:"" help:"Run as a server to periodically send notifications."`
+	ConfigFile kong.ConfigFlag       `name:"config" type:"existingfile" help:"YAML or JSON file to load flags from."`
+	LogConfig  LogConfig             `embed:"" group:"Logging Flags:"`
+	Generate   flashcard.GenerateCMD `cmd:"" default:"withargs" help:"Generate all flashcards."`
+	Lint       flashcard.LintCMD     `cmd:"" help:"Check textbook files for mistakes."`
+	Notify     notify.NotifyCMD      `cmd:"" help:"Use generated flashcards to notify."`
+	Serve      serve.CMD             `cmd:"" help:"Run as a server to periodically send notifications."`
+	Roles      serve.RolesCMD        `cmd:"" help:"Manage the roles each deck mentions."`
+	Export     export.CMD            `cmd:"" help:"Export flashcards to other formats."`
+	Import     importer.CMD          `cmd:"" help:"Import flashcards from other formats into the database."`
+	Config     config.CMD            `cmd:"" help:"Inspect configuration."`
 }
 
 func main() {
@@ -36,6 +45,10 @@ func main() {
 	defer stop()
 	if err := Run(ctx, os.Stdin, os.Stdout, os.Stderr, os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
+		var coder kong.ExitCoder
+		if errors.As(err, &coder) {
+			os.Exit(coder.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
@@ -43,10 +56,13 @@ func main() {
 func Run(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer, args []string) error {
 	var exit bool
 	var cmd Cmd
+	files := &config.Files{}
 	parser, err := kong.New(
 		&cmd,
 		kong.Exit(func(_ int) { exit = true }),
 		konghelp.Help(),
+		kong.Configuration(files.Load),
+		kong.Bind(files),
 		kong.BindTo(ctx, new(context.Context)),
 		kong.BindTo(stdout, new(io.Writer)),
 		kong.BindTo(stdin, new(io.Reader)),
@@ -59,7 +75,7 @@ func Run(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Write
 	parser.Stderr = stdout
 
 	context, err := parser.Parse(
-		os.Args[1:],
+		args,
 	)
 	if errors.Is(err, ErrDone) {
 		return nil
@@ -69,6 +85,11 @@ func Run(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Write
 
 	logger := cmd.LogConfig.NewLogger(stderr)
 	if err := context.Run(logger); err != nil {
+		// Commands that want a specific exit code (ex: lint in CI) get one.
+		var coder kong.ExitCoder
+		if errors.As(err, &coder) {
+			return err
+		}
 		// TODO: Handle some of the run options
 		logger.Error("failed to run", "error", err)
 		return nil
@@ -2,45 +2,54 @@ package notify
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/ohhfishal/fishy/discord"
 	"github.com/ohhfishal/fishy/flashcard"
-	"github.com/ohhfishal/fishy/version"
 )
 
 type DiscordCMD struct {
-	Webhook      string       `arg:"" required:"" help:"Discord webhook to send message to."`
-	File         string       `default:"out.json" type:"existingfile" help:"Fish file to load flashscard from."`
-	EmbedOptions EmbedOptions `embed:"" group:"Embed Options"`
-	DryRun       bool         `help:"Don't send the message and print it to stdout instead."`
+	Webhook      string           `arg:"" required:"" env:"FISHY_WEBHOOK" secret:"" help:"Discord webhook to send message to."`
+	File         string           `default:"out.json" type:"existingfile" help:"Fish file to load flashscard from."`
+	Filter       flashcard.Filter `embed:"" group:"Filter"`
+	EmbedOptions EmbedOptions     `embed:"" group:"Embed Options"`
+	ThreadID     string           `xor:"thread" help:"Existing thread to post in."`
+	ThreadName   string           `xor:"thread" help:"Create a forum post with this name. The webhook must be for a forum channel."`
+	DryRun       bool             `help:"Don't send the message and print it to stdout instead."`
 }
 
 func (config *DiscordCMD) Run(ctx context.Context, logger *slog.Logger) error {
-	file, err := os.Open(config.File)
+	cards, err := flashcard.ReadFlashcards(config.File)
 	if err != nil {
-		return fmt.Errorf("opening file %s: %w", config.File, err)
-	}
-	defer file.Close()
-
-	var cards []flashcard.Flashcard
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&cards); err != nil {
-		return fmt.Errorf("reading file: %w", err)
+		return err
 	}
 
 	logger.Debug("read cards successfully", "total", len(cards))
+	if err := config.Filter.Validate(); err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+	cards = config.Filter.Apply(cards)
+	if len(cards) == 0 {
+		return errors.New("no cards match the filter")
+	}
 
 	selected := cards[rand.Int()%len(cards)]
-	embed := Embed(selected, config.EmbedOptions)
+	embed, err := Embed(ctx, selected, config.EmbedOptions)
+	if err != nil {
+		return err
+	}
+	embed.ThreadID = config.ThreadID
+	embed.ThreadName = config.ThreadName
 	slog.Info("sending", "embed", embed)
 	if config.DryRun {
-		return nil
+		return embed.Validate()
 	}
 	if err := embed.Post(config.Webhook); err != nil {
 		return fmt.Errorf("could not post embed: %v: %w", embed, err)
@@ -51,59 +60,138 @@ func (config *DiscordCMD) Run(ctx context.Context, logger *slog.Logger) error {
 }
 
 type EmbedOptions struct {
-	Mentions []string `short:"m" help:"List of mentions to add."`
-	// TODO: Add options
-	// Spoil description
+	Mentions []string `short:"m" help:"Users or roles to mention (ex: <@123>, <@&456>, role:456 or @here). Only these are pinged."`
+	Template string   `name:"embed-template" type:"existingfile" help:"YAML file of text/templates describing how cards look. Defaults to a built in template (notify/embed.yaml)."`
 }
 
-func Embed(card flashcard.Flashcard, opts EmbedOptions) discord.Embed {
-	fields := []discord.Field{
-		{
-			Name:  "Source",
-			Value: card.Origin,
-		},
-	}
-	if card.ClassContext != "" {
-		fields = append(fields, discord.Field{
-			Name:  "Textbook",
-			Value: card.ClassContext,
-		})
-	}
-	if len(card.AIOverview) > 0 {
-		fields = append(fields, discord.Field{
-			Name:  "AI Summary",
-			Value: ConvertToBullets(card.AIOverview),
-		})
-	}
-	var thumbnail discord.Image
-	if card.Thumbnail.Source != "" {
-		thumbnail = discord.Image{
-			URL:    card.Thumbnail.Source,
-			Width:  card.Thumbnail.Width,
-			Height: card.Thumbnail.Height,
+// Embed renders card with the template in opts (see DefaultTemplate), fitting
+// it within Discord's limits. Only opts.Mentions are pinged, whatever the
+// card says.
+func Embed(ctx context.Context, card flashcard.Flashcard, opts EmbedOptions) (discord.Embed, error) {
+	var embed discord.Embed
+	mentions, err := discord.ParseMentions(opts.Mentions)
+	if err != nil {
+		return embed, err
+	}
+	tmpl, err := LoadTemplate(ctx, opts.Template)
+	if err != nil {
+		return embed, err
+	}
+	message, err := tmpl.Render(card)
+	if err != nil {
+		return embed, fmt.Errorf("rendering embed: %w", err)
+	}
+
+	message.Title = discord.Sanitize(message.Title)
+	message.Description = discord.Sanitize(message.Description)
+	message.Footer.Text = discord.Sanitize(message.Footer.Text)
+	message.Author.Name = discord.Sanitize(message.Author.Name)
+	for i := range message.Fields {
+		message.Fields[i].Name = discord.Sanitize(message.Fields[i].Name)
+		message.Fields[i].Value = discord.Sanitize(message.Fields[i].Value)
+	}
+
+	description := message.Description
+	message.Title = Fit(message.Title, discord.MaxTitle)
+	message.Description = Fit(description, discord.MaxDescription)
+	message.Footer.Text = Fit(message.Footer.Text, discord.MaxFooter)
+	message.Author.Name = Fit(message.Author.Name, discord.MaxAuthorName)
+	for i := range message.Fields {
+		message.Fields[i].Name = Fit(message.Fields[i].Name, discord.MaxFieldName)
+		message.Fields[i].Value = Fit(message.Fields[i].Value, discord.MaxFieldValue)
+	}
+	// The description is what usually pushes an embed over the total.
+	if over := message.Length() - discord.MaxTotal; over > 0 {
+		message.Description = Fit(description, discord.Length(message.Description)-over)
+	}
+
+	thumbnail := discord.Image{
+		URL:    card.Thumbnail.Source,
+		Width:  card.Thumbnail.Width,
+		Height: card.Thumbnail.Height,
+	}
+	// Prefer uploading the mirrored copy so the embed works even if the
+	// original URL moved.
+	if path := card.Thumbnail.Path; path != "" && exists(path) {
+		thumbnail.URL = embed.Attach(filepath.Base(path), path)
+	}
+	if thumbnail.URL == "" {
+		thumbnail = discord.Image{URL: card.Style.Thumbnail}
+	}
+	message.Image = thumbnail
+	// Discord hides attachments named SPOILER_* until clicked, like the
+	// description.
+	if path := card.Reveal.Path; path != "" && exists(path) {
+		embed.Attach("SPOILER_"+filepath.Base(path), path)
+	}
+	embed.Mention(mentions...)
+	embed.Messages = []discord.Message{message}
+	return embed, nil
+}
+
+// Fit truncates text to limit (see Truncate), keeping it spoilered if all of
+// it was.
+func Fit(text string, limit int) string {
+	if inner, ok := strings.CutPrefix(text, "||"); ok && len(inner) >= 2 && strings.HasSuffix(inner, "||") && !strings.Contains(inner[:len(inner)-2], "||") {
+		return Spoiler(inner[:len(inner)-2], limit)
+	}
+	return Truncate(text, limit)
+}
+
+// Ellipsis marks text cut short in the middle of a sentence.
+const Ellipsis = "…"
+
+// Truncate shortens text to at most limit characters (see discord.Length),
+// ending after the last sentence that fits. If not even the first sentence
+// fits it is cut between words and ends with Ellipsis.
+func Truncate(text string, limit int) string {
+	if discord.Length(text) <= limit {
+		return text
+	} else if limit < discord.Length(Ellipsis)+1 {
+		return ""
+	}
+
+	var fits string
+	for _, end := range flashcard.SentenceEnds(text) {
+		if discord.Length(text[:end]) > limit {
+			break
 		}
+		fits = text[:end]
 	}
-	return discord.Embed{
-		Content: strings.Join(opts.Mentions, " "),
-		Messages: []discord.Message{
-			{
-				Title:       card.Header,
-				Description: fmt.Sprintf("||%s||", card.Description),
-				Color:       0x5865F2,
-				Fields:      fields,
-				Footer: discord.Footer{
-					Text: fmt.Sprintf("fishy %s • %s", version.Version(), version.Repo),
-				},
-				Image: thumbnail,
-			},
-		},
+	if fits = strings.TrimSpace(fits); fits != "" {
+		return fits
 	}
+
+	cut := string([]rune(text)[:limit-discord.Length(Ellipsis)])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + Ellipsis
 }
 
+// Spoiler hides text until clicked, truncating it so the markup still fits
+// in limit.
+func Spoiler(text string, limit int) string {
+	return fmt.Sprintf("||%s||", Truncate(text, limit-4))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ConvertToBullets spoilers each line in a list, leaving off lines that would
+// go over discord.MaxFieldValue.
 func ConvertToBullets(lines []string) string {
 	var builder strings.Builder
 	for _, line := range lines {
-		builder.WriteString(fmt.Sprintf("- ||%s||\n", line))
+		// A single line too long for the field is truncated instead.
+		bullet := fmt.Sprintf("- %s\n", Spoiler(line, discord.MaxFieldValue-2))
+		// The last line break is trimmed so it does not count.
+		if discord.Length(builder.String()+bullet) > discord.MaxFieldValue+1 {
+			break
+		}
+		builder.WriteString(bullet)
 	}
 	return strings.TrimSpace(builder.String())
 }
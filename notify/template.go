@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+	"github.com/ohhfishal/fishy/discord"
+	"github.com/ohhfishal/fishy/flashcard"
+	"github.com/ohhfishal/fishy/version"
+)
+
+// DefaultTemplate is used when EmbedOptions.Template is unset.
+//
+//go:embed embed.yaml
+var DefaultTemplate string
+
+var defaultTemplate = func() *Template {
+	parsed, err := ParseTemplate(context.Background(), []byte(DefaultTemplate))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+var templateFuncs = template.FuncMap{
+	"spoiler": func(text string) string {
+		if text == "" {
+			return ""
+		}
+		return "||" + text + "||"
+	},
+	"bullets": ConvertToBullets,
+	"join":    strings.Join,
+	"version": version.Version,
+	"repo":    func() string { return version.Repo },
+}
+
+// EmbedTemplate maps a card to the parts of its embed. Each string is a
+// text/template executed with the card.
+type EmbedTemplate struct {
+	Title       string          `yaml:"title"`
+	Description string          `yaml:"description"`
+	Color       string          `yaml:"color"`
+	Fields      []FieldTemplate `yaml:"fields"`
+	Footer      string          `yaml:"footer"`
+	Author      AuthorTemplate  `yaml:"author"`
+}
+
+// AuthorTemplate is left out of the embed if Name renders empty.
+type AuthorTemplate struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	Icon string `yaml:"icon"`
+}
+
+type FieldTemplate struct {
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value"`
+	Inline bool   `yaml:"inline"`
+}
+
+// Template is a parsed EmbedTemplate.
+type Template struct {
+	EmbedTemplate
+	templates *template.Template
+}
+
+// LoadTemplate parses the template at path, or returns the default one if path
+// is empty.
+func LoadTemplate(ctx context.Context, path string) (*Template, error) {
+	if path == "" {
+		return defaultTemplate, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	parsed, err := ParseTemplate(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+func ParseTemplate(ctx context.Context, data []byte) (*Template, error) {
+	var embed EmbedTemplate
+	if err := yaml.UnmarshalContext(ctx, data, &embed, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	templates := template.New("embed").Funcs(templateFuncs).Option("missingkey=error")
+	parse := func(name string, text string) error {
+		if _, err := templates.New(name).Parse(text); err != nil {
+			return fmt.Errorf("parsing %s: %w", name, err)
+		}
+		return nil
+	}
+	if err := parse("title", embed.Title); err != nil {
+		return nil, err
+	} else if err := parse("description", embed.Description); err != nil {
+		return nil, err
+	} else if err := parse("color", embed.Color); err != nil {
+		return nil, err
+	} else if err := parse("footer", embed.Footer); err != nil {
+		return nil, err
+	} else if err := parse("author.name", embed.Author.Name); err != nil {
+		return nil, err
+	} else if err := parse("author.url", embed.Author.URL); err != nil {
+		return nil, err
+	} else if err := parse("author.icon", embed.Author.Icon); err != nil {
+		return nil, err
+	}
+	for i, field := range embed.Fields {
+		if err := parse(fmt.Sprintf("fields[%d].name", i), field.Name); err != nil {
+			return nil, err
+		} else if err := parse(fmt.Sprintf("fields[%d].value", i), field.Value); err != nil {
+			return nil, err
+		}
+	}
+	return &Template{EmbedTemplate: embed, templates: templates}, nil
+}
+
+// Render fills in the text of a message for card. Limits are not checked.
+func (tmpl *Template) Render(card flashcard.Flashcard) (discord.Message, error) {
+	var message discord.Message
+	var err error
+	if message.Title, err = tmpl.execute("title", card); err != nil {
+		return message, err
+	} else if message.Description, err = tmpl.execute("description", card); err != nil {
+		return message, err
+	} else if message.Footer.Text, err = tmpl.execute("footer", card); err != nil {
+		return message, err
+	} else if message.Author.Name, err = tmpl.execute("author.name", card); err != nil {
+		return message, err
+	} else if message.Author.Url, err = tmpl.execute("author.url", card); err != nil {
+		return message, err
+	} else if message.Author.IconURL, err = tmpl.execute("author.icon", card); err != nil {
+		return message, err
+	}
+	// Discord rejects authors without a name.
+	if message.Author.Name == "" {
+		message.Author = discord.Author{}
+	}
+
+	color, err := tmpl.execute("color", card)
+	if err != nil {
+		return message, err
+	} else if message.Color, err = flashcard.ParseColor(color); err != nil {
+		return message, err
+	}
+
+	for i, field := range tmpl.Fields {
+		name, err := tmpl.execute(fmt.Sprintf("fields[%d].name", i), card)
+		if err != nil {
+			return message, err
+		}
+		value, err := tmpl.execute(fmt.Sprintf("fields[%d].value", i), card)
+		if err != nil {
+			return message, err
+		}
+		// Discord rejects fields without both.
+		if name == "" || value == "" {
+			continue
+		}
+		message.Fields = append(message.Fields, discord.Field{
+			Name:   name,
+			Value:  value,
+			Inline: field.Inline,
+		})
+	}
+	return message, nil
+}
+
+func (tmpl *Template) execute(name string, card flashcard.Flashcard) (string, error) {
+	var buffer bytes.Buffer
+	if err := tmpl.templates.ExecuteTemplate(&buffer, name, card); err != nil {
+		return "", fmt.Errorf("executing %s: %w", name, err)
+	}
+	return strings.TrimSpace(buffer.String()), nil
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ohhfishal/fishy/flashcard"
+	"github.com/ohhfishal/fishy/version"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata.")
+
+var forward = flashcard.Flashcard{
+	Header:       "Photosynthesis",
+	Description:  "Photosynthesis is a process used by plants to convert light energy into chemical energy.",
+	Origin:       "[wikipedia](https://en.wikipedia.org/wiki/Photosynthesis)",
+	Textbook:     "Campbell Biology",
+	Subject:      "Biology",
+	Chapter:      10,
+	ChapterTitle: "Photosynthesis",
+	Thumbnail: flashcard.Image{
+		Source: "https://upload.wikimedia.org/leaf.png",
+		Width:  320,
+		Height: 240,
+	},
+}
+
+// TestDefaultTemplate checks the default template renders cards the same way
+// notify.Embed did before it was templated. Run with -update to accept
+// intended changes.
+func TestDefaultTemplate(t *testing.T) {
+	reverse := forward
+	reverse.Direction = flashcard.DirectionReverse
+	reverse.Description = "_____ is a process used by plants to convert light energy into chemical energy."
+
+	overview := forward
+	overview.AIOverview = []string{
+		"Happens in the chloroplasts.",
+		"Releases oxygen as a by-product.",
+	}
+	overview.ClassContext = "Midterm 2"
+
+	occlusion := flashcard.Flashcard{
+		Header:      "Heart",
+		Description: "Left ventricle",
+		Origin:      "heart.png#Left ventricle",
+		Kind:        flashcard.KindOcclusion,
+		Thumbnail:   flashcard.Image{Path: filepath.Join("testdata", "masked.png"), Width: 4, Height: 4},
+		Reveal:      flashcard.Image{Path: filepath.Join("testdata", "revealed.png"), Width: 4, Height: 4},
+	}
+
+	tests := []struct {
+		Name string
+		Card flashcard.Flashcard
+	}{
+		{Name: "forward", Card: forward},
+		{Name: "reverse", Card: reverse},
+		{Name: "occlusion", Card: occlusion},
+		{Name: "overview", Card: overview},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			embed, err := Embed(context.Background(), test.Card, EmbedOptions{})
+			if err != nil {
+				t.Fatalf("rendering: %v", err)
+			}
+			data, err := json.MarshalIndent(embed, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(data) + "\n"
+			// The footer has the version of the test binary.
+			if v := version.Version(); v != "" {
+				got = strings.ReplaceAll(got, "fishy "+v+" ", "fishy VERSION ")
+			}
+
+			path := filepath.Join("testdata", test.Name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("embed does not match %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/ohhfishal/fishy/discord"
+	"github.com/ohhfishal/fishy/flashcard"
+	"github.com/ohhfishal/fishy/notify"
+)
+
+// Deck is a pool of cards sent to its own webhooks on its own schedule. Each
+// deck keeps its own job history in the database.
+type Deck struct {
+	// Name identifies the deck's jobs. The deck built from flags has no name.
+	Name     string           `yaml:"name"`
+	Filter   flashcard.Filter `yaml:"filter"`
+	Webhooks []string         `yaml:"webhooks"`
+	Mentions []string         `yaml:"mentions"`
+	// Embed template file (see notify.EmbedOptions).
+	Template string `yaml:"template"`
+	// How cards are threaded (none, card or day).
+	Threads string `yaml:"threads"`
+
+	// Zero values are replaced by the matching server flag.
+	Interval    time.Duration `yaml:"interval"`
+	Probability *float64      `yaml:"probability"`
+	Delta       *float64      `yaml:"delta"`
+
+	ticking sync.Mutex
+}
+
+type deckRoot struct {
+	Decks []*Deck `yaml:"decks"`
+}
+
+func ParseDecks(ctx context.Context, reader io.Reader) ([]*Deck, error) {
+	var root deckRoot
+	decoder := yaml.NewDecoder(reader, yaml.DisallowUnknownField())
+	if err := decoder.DecodeContext(ctx, &root); err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+	return root.Decks, nil
+}
+
+// LoadDecks returns the decks to serve. Without a decks file a single unnamed
+// deck is built from the flags.
+func (config *ServerConfig) LoadDecks(ctx context.Context) ([]*Deck, error) {
+	if config.Decks == "" {
+		if config.Webhook == "" {
+			return nil, errors.New("a webhook is required when not using --decks")
+		} else if err := config.Filter.Validate(); err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		} else if _, err := notify.LoadTemplate(ctx, config.EmbedOptions.Template); err != nil {
+			return nil, err
+		} else if _, err := discord.ParseMentions(config.EmbedOptions.Mentions); err != nil {
+			return nil, err
+		}
+		return []*Deck{config.defaults(&Deck{
+			Webhooks: []string{config.Webhook},
+		})}, nil
+	}
+
+	if config.Webhook != "" {
+		return nil, errors.New("a webhook can not be used with --decks (set webhooks in the decks file)")
+	}
+
+	file, err := os.Open(config.Decks)
+	if err != nil {
+		return nil, fmt.Errorf("opening file %s: %w", config.Decks, err)
+	}
+	defer file.Close()
+
+	decks, err := ParseDecks(ctx, file)
+	if err != nil {
+		return nil, err
+	} else if len(decks) == 0 {
+		return nil, fmt.Errorf("no decks in %s", config.Decks)
+	}
+
+	seen := map[string]bool{}
+	for i, deck := range decks {
+		if deck.Name == "" {
+			return nil, fmt.Errorf("deck %d: missing name", i)
+		} else if seen[deck.Name] {
+			return nil, fmt.Errorf("deck %s: duplicate name", deck.Name)
+		} else if len(deck.Webhooks) == 0 {
+			return nil, fmt.Errorf("deck %s: no webhooks", deck.Name)
+		} else if err := deck.Filter.Validate(); err != nil {
+			return nil, fmt.Errorf("deck %s: filter: %w", deck.Name, err)
+		}
+		seen[deck.Name] = true
+		config.defaults(deck)
+		if _, err := notify.LoadTemplate(ctx, deck.Template); err != nil {
+			return nil, fmt.Errorf("deck %s: %w", deck.Name, err)
+		} else if _, err := discord.ParseMentions(deck.Mentions); err != nil {
+			return nil, fmt.Errorf("deck %s: %w", deck.Name, err)
+		} else if !slices.Contains([]string{ThreadsNone, ThreadsCard, ThreadsDay}, deck.Threads) {
+			return nil, fmt.Errorf("deck %s: invalid threads %q (expected none, card or day)", deck.Name, deck.Threads)
+		}
+	}
+	return decks, nil
+}
+
+// defaults fills in any unset deck settings from the flags.
+func (config *ServerConfig) defaults(deck *Deck) *Deck {
+	if deck.Interval == 0 {
+		deck.Interval = config.Interval
+	}
+	if deck.Probability == nil {
+		deck.Probability = &config.Probability
+	}
+	if deck.Delta == nil {
+		deck.Delta = &config.Delta
+	}
+	if len(deck.Mentions) == 0 {
+		deck.Mentions = config.EmbedOptions.Mentions
+	}
+	if deck.Template == "" {
+		deck.Template = config.EmbedOptions.Template
+	}
+	if deck.Threads == "" {
+		deck.Threads = config.Threads
+	}
+	if deck.Filter.Empty() {
+		deck.Filter = config.Filter
+	}
+	return deck
+}
+
+func (deck *Deck) EmbedOptions() notify.EmbedOptions {
+	return notify.EmbedOptions{
+		Mentions: deck.Mentions,
+		Template: deck.Template,
+	}
+}
+
+// lease is the name of the lease held while ticking the deck.
+func (deck *Deck) lease() string {
+	if deck.Name == "" {
+		return TickLease
+	}
+	return TickLease + ":" + deck.Name
+}
@@ -0,0 +1,115 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ohhfishal/fishy/database"
+)
+
+var ErrNoTick = errors.New("no tick yet")
+
+// Health tracks when the server last did work so it can report readiness.
+type Health struct {
+	mu       sync.Mutex
+	lastTick time.Time
+	sendErrs map[string]error
+}
+
+func (health *Health) Tick() {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	health.lastTick = time.Now()
+}
+
+// Send records the result of the last post for deck.
+func (health *Health) Send(deck string, err error) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	if health.sendErrs == nil {
+		health.sendErrs = map[string]error{}
+	}
+	health.sendErrs[deck] = err
+}
+
+// Ticking returns an error if no tick has started within maxAge.
+func (health *Health) Ticking(maxAge time.Duration) error {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	if health.lastTick.IsZero() {
+		return ErrNoTick
+	} else if age := time.Since(health.lastTick); age > maxAge {
+		return fmt.Errorf("last tick was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Sending returns the errors from the last webhook post of each deck, if any.
+func (health *Health) Sending() error {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	var errs []error
+	for _, deck := range slices.Sorted(maps.Keys(health.sendErrs)) {
+		if err := health.sendErrs[deck]; err != nil {
+			errs = append(errs, fmt.Errorf("deck %q: %w", deck, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type check struct {
+	Name string
+	Err  error
+}
+
+// Checks runs every readiness check against the server.
+func (config *ServerConfig) Checks(ctx context.Context, db *database.Store) []check {
+	return []check{
+		{Name: "database", Err: db.Ping(ctx)},
+		{Name: "tick", Err: config.health.Ticking(config.maxTickAge())},
+		{Name: "webhook", Err: config.health.Sending()},
+	}
+}
+
+// Allow a missed heartbeat before considering the event loop stuck.
+func (config *ServerConfig) maxTickAge() time.Duration {
+	return 2*config.Heartbeat + TickTimeout
+}
+
+func HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+func (config *ServerConfig) ReadyzHandler(db *database.Store, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		var body strings.Builder
+		status := http.StatusOK
+		for _, check := range config.Checks(ctx, db) {
+			if check.Err != nil {
+				status = http.StatusServiceUnavailable
+				fmt.Fprintf(&body, "%s: %s\n", check.Name, check.Err)
+			} else {
+				fmt.Fprintf(&body, "%s: ok\n", check.Name)
+			}
+		}
+		if status != http.StatusOK {
+			logger.Warn("not ready", "checks", body.String())
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body.String())
+	})
+}
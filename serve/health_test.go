@@ -0,0 +1,87 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohhfishal/fishy/database"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Setup    func(health *Health, db *database.Store)
+		Expected int
+		Body     string
+	}{
+		{
+			Name:     "before startup",
+			Setup:    func(health *Health, db *database.Store) {},
+			Expected: http.StatusServiceUnavailable,
+			Body:     "tick: no tick yet",
+		},
+		{
+			Name: "started",
+			Setup: func(health *Health, db *database.Store) {
+				health.Tick()
+			},
+			Expected: http.StatusOK,
+			Body:     "database: ok\ntick: ok\nwebhook: ok\n",
+		},
+		{
+			Name: "sent",
+			Setup: func(health *Health, db *database.Store) {
+				health.Tick()
+				health.Send("", nil)
+			},
+			Expected: http.StatusOK,
+			Body:     "webhook: ok",
+		},
+		{
+			Name: "webhook failing",
+			Setup: func(health *Health, db *database.Store) {
+				health.Tick()
+				health.Send("biology", errors.New("got status 404"))
+			},
+			Expected: http.StatusServiceUnavailable,
+			Body:     `webhook: deck "biology": got status 404`,
+		},
+		{
+			Name: "stuck",
+			Setup: func(health *Health, db *database.Store) {
+				health.lastTick = time.Now().Add(-time.Hour)
+			},
+			Expected: http.StatusServiceUnavailable,
+			Body:     "tick: last tick was",
+		},
+		{
+			Name: "database down",
+			Setup: func(health *Health, db *database.Store) {
+				health.Tick()
+				db.Close()
+			},
+			Expected: http.StatusServiceUnavailable,
+			Body:     "database: sql: database is closed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			db := connect(t, filepath.Join(t.TempDir(), "fishy.db"))
+			config := &ServerConfig{Heartbeat: time.Minute, health: &Health{}}
+			test.Setup(config.health, db)
+
+			recorder := httptest.NewRecorder()
+			config.ReadyzHandler(db, discard).ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+			if recorder.Code != test.Expected {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, test.Expected, recorder.Body)
+			} else if !strings.Contains(recorder.Body.String(), test.Body) {
+				t.Errorf("got body %q, want it to contain %q", recorder.Body, test.Body)
+			}
+		})
+	}
+}
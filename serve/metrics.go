@@ -0,0 +1,229 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/discord"
+)
+
+// Upper bounds (in seconds) of the webhook latency histogram buckets.
+var LatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics counts what the server has done since it started. It is written out
+// in the Prometheus text exposition format by Handler.
+type Metrics struct {
+	mu      sync.Mutex
+	decks   map[string]*deckMetrics
+	latency histogram
+}
+
+type deckMetrics struct {
+	ticks         int64
+	rolls         int64
+	skips         int64
+	sends         int64
+	sendFailures  map[string]int64
+	failureStreak int64
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		decks: map[string]*deckMetrics{},
+		latency: histogram{
+			buckets: LatencyBuckets,
+			counts:  make([]int64, len(LatencyBuckets)),
+		},
+	}
+}
+
+// deck must be called with mu held.
+func (metrics *Metrics) deck(name string) *deckMetrics {
+	deck, ok := metrics.decks[name]
+	if !ok {
+		deck = &deckMetrics{sendFailures: map[string]int64{}}
+		metrics.decks[name] = deck
+	}
+	return deck
+}
+
+func (metrics *Metrics) Tick(deck string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.deck(deck).ticks++
+}
+
+// Roll records a roll of the dice and whether it was skipped.
+func (metrics *Metrics) Roll(deck string, skipped bool) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.deck(deck).rolls++
+	if skipped {
+		metrics.deck(deck).skips++
+	}
+}
+
+func (metrics *Metrics) FailureStreak(deck string, failures int64) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.deck(deck).failureStreak = failures
+}
+
+// Send records the outcome of posting to a webhook and how long it took.
+func (metrics *Metrics) Send(deck string, duration time.Duration, err error) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.latency.observe(duration.Seconds())
+	if err == nil {
+		metrics.deck(deck).sends++
+		return
+	}
+
+	// Errors without a response (timeouts, DNS, ...) are grouped as "error".
+	status := "error"
+	var responseErr *discord.ResponseError
+	if errors.As(err, &responseErr) {
+		status = strconv.Itoa(responseErr.StatusCode)
+	}
+	metrics.deck(deck).sendFailures[status]++
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// Handler serves the metrics along with the current card counts from db.
+func (metrics *Metrics) Handler(db *database.Store, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counts, err := db.CountCards(r.Context())
+		if err != nil {
+			logger.Error("counting cards", "err", err)
+			http.Error(w, "counting cards", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := metrics.Write(w, counts); err != nil {
+			logger.Warn("writing metrics", "err", err)
+		}
+	})
+}
+
+// Write writes the metrics in the Prometheus text exposition format.
+func (metrics *Metrics) Write(writer io.Writer, counts []database.CountCardsRow) error {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	names := slices.Sorted(maps.Keys(metrics.decks))
+	w := &promWriter{w: writer}
+	w.metric("fishy_ticks_total", "counter", "Heartbeats that checked for work.")
+	for _, name := range names {
+		w.sample("fishy_ticks_total", []string{"deck", name}, float64(metrics.decks[name].ticks))
+	}
+
+	w.metric("fishy_rolls_total", "counter", "Rolls made to decide whether to send a card.")
+	for _, name := range names {
+		w.sample("fishy_rolls_total", []string{"deck", name}, float64(metrics.decks[name].rolls))
+	}
+
+	w.metric("fishy_skips_total", "counter", "Rolls that did not send a card.")
+	for _, name := range names {
+		w.sample("fishy_skips_total", []string{"deck", name}, float64(metrics.decks[name].skips))
+	}
+
+	w.metric("fishy_sends_total", "counter", "Cards successfully posted to a webhook.")
+	for _, name := range names {
+		w.sample("fishy_sends_total", []string{"deck", name}, float64(metrics.decks[name].sends))
+	}
+
+	w.metric("fishy_send_failures_total", "counter", "Failed webhook posts by response status.")
+	for _, name := range names {
+		failures := metrics.decks[name].sendFailures
+		for _, status := range slices.Sorted(maps.Keys(failures)) {
+			w.sample("fishy_send_failures_total", []string{"deck", name, "status", status}, float64(failures[status]))
+		}
+	}
+
+	w.metric("fishy_failure_streak", "gauge", "Rolls skipped since the last card was sent.")
+	for _, name := range names {
+		w.sample("fishy_failure_streak", []string{"deck", name}, float64(metrics.decks[name].failureStreak))
+	}
+
+	w.metric("fishy_cards", "gauge", "Cards in the database by textbook and chapter.")
+	for _, count := range counts {
+		w.sample("fishy_cards", []string{"textbook", count.Textbook, "chapter", strconv.FormatInt(count.Chapter, 10)}, float64(count.Cards))
+	}
+
+	w.metric("fishy_webhook_duration_seconds", "histogram", "Time taken to post to the webhook.")
+	for i, bound := range metrics.latency.buckets {
+		w.sample("fishy_webhook_duration_seconds_bucket", []string{"le", formatFloat(bound)}, float64(metrics.latency.counts[i]))
+	}
+	w.sample("fishy_webhook_duration_seconds_bucket", []string{"le", "+Inf"}, float64(metrics.latency.count))
+	w.sample("fishy_webhook_duration_seconds_sum", nil, metrics.latency.sum)
+	w.sample("fishy_webhook_duration_seconds_count", nil, float64(metrics.latency.count))
+	return w.err
+}
+
+// promWriter writes the text exposition format, keeping the first error.
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *promWriter) metric(name string, kind string, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes a single sample. labels are key value pairs.
+func (w *promWriter) sample(name string, labels []string, value float64) {
+	var builder strings.Builder
+	builder.WriteString(name)
+	if len(labels) > 0 {
+		builder.WriteString("{")
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				builder.WriteString(",")
+			}
+			builder.WriteString(fmt.Sprintf(`%s="%s"`, labels[i], escapeLabel(labels[i+1])))
+		}
+		builder.WriteString("}")
+	}
+	w.printf("%s %s\n", builder.String(), formatFloat(value))
+}
+
+func (w *promWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,125 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/discord"
+)
+
+// RolesCMD manages the roles each deck mentions, which are kept in the
+// database so they can change without restarting the server.
+type RolesCMD struct {
+	Add    RolesAddCMD    `cmd:"" help:"Mention a role whenever a deck sends a card."`
+	Remove RolesRemoveCMD `cmd:"" help:"Stop mentioning a role."`
+	List   RolesListCMD   `cmd:"" help:"List the roles each deck mentions."`
+}
+
+type RoleArgs struct {
+	Role     string `arg:"" help:"Role ID or mention (ex: 456 or <@&456>)."`
+	Deck     string `short:"d" help:"Deck to mention the role in. Empty for the deck built from flags."`
+	Database string `default:"fishy.db" help:"SQLite connection string."`
+}
+
+// ID returns the ID of the role.
+func (args RoleArgs) ID() (string, error) {
+	role := args.Role
+	if snowflake(role) {
+		role = discord.MentionRole + ":" + role
+	}
+	mention, err := discord.ParseMention(role)
+	if err != nil {
+		return "", err
+	} else if mention.Type != discord.MentionRole {
+		return "", fmt.Errorf("%s is not a role", args.Role)
+	}
+	return mention.ID, nil
+}
+
+type RolesAddCMD struct {
+	RoleArgs `embed:""`
+}
+
+func (config *RolesAddCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	role, err := config.ID()
+	if err != nil {
+		return err
+	}
+	db, err := database.Connect(ctx, "sqlite", config.Database)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.AddDeckRole(ctx, database.AddDeckRoleParams{Deck: config.Deck, Role: role}); err != nil {
+		return fmt.Errorf("adding role: %w", err)
+	}
+	logger.Info("added role", "deck", config.Deck, "role", role)
+	return nil
+}
+
+type RolesRemoveCMD struct {
+	RoleArgs `embed:""`
+}
+
+func (config *RolesRemoveCMD) Run(ctx context.Context, logger *slog.Logger) error {
+	role, err := config.ID()
+	if err != nil {
+		return err
+	}
+	db, err := database.Connect(ctx, "sqlite", config.Database)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	removed, err := db.RemoveDeckRole(ctx, database.RemoveDeckRoleParams{Deck: config.Deck, Role: role})
+	if err != nil {
+		return fmt.Errorf("removing role: %w", err)
+	} else if removed == 0 {
+		return errors.New("deck does not mention that role")
+	}
+	logger.Info("removed role", "deck", config.Deck, "role", role)
+	return nil
+}
+
+type RolesListCMD struct {
+	Database string `default:"fishy.db" help:"SQLite connection string."`
+}
+
+func (config *RolesListCMD) Run(ctx context.Context, stdout io.Writer) error {
+	db, err := database.Connect(ctx, "sqlite", config.Database)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	roles, err := db.ListDeckRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("listing roles: %w", err)
+	}
+	for _, role := range roles {
+		deck := role.Deck
+		if deck == "" {
+			deck = "(default)"
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", deck, discord.Mention{Type: discord.MentionRole, ID: role.Role})
+	}
+	return nil
+}
+
+func snowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
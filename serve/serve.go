@@ -5,34 +5,85 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/discord"
+	"github.com/ohhfishal/fishy/flashcard"
 	"github.com/ohhfishal/fishy/notify"
 	"log/slog"
 	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 )
 
 var ErrSkip = errors.New("no work to do")
 
+const (
+	// TickTimeout bounds how long a single tick may take.
+	TickTimeout = 30 * time.Second
+	// WriteTimeout bounds recording a job, which is not cut short by shutdown.
+	WriteTimeout = 10 * time.Second
+	// TickLease is held while ticking so replicas sharing a database take turns.
+	TickLease = "tick"
+	LeaseTTL  = TickTimeout + WriteTimeout
+)
+
 type CMD struct {
 	Config ServerConfig `embed:"" group:"Server Config"`
+	Args   []string     `arg:"" optional:"" secret:"" help:"Deprecated: <webhook> [database]. Use --webhook and --database."`
 }
 
 type ServerConfig struct {
-	Webhook      string              `arg:"" required:"" help:"Discord webhook to send message to."`
-	Database     string              `arg:"" default:"fishy.db" help:"SQLite connection string."`
+	Webhook      string              `env:"FISHY_WEBHOOK" secret:"" help:"Discord webhook to send message to. Required without --decks."`
+	Database     string              `default:"fishy.db" env:"FISHY_DATABASE" help:"SQLite connection string."`
 	EmbedOptions notify.EmbedOptions `embed:""`
+	Filter       flashcard.Filter    `embed:"" group:"Filter"`
 	CardFile     string              `name:"load" short:"l" type:"existingfile" help:"Generated flashcard file to load in. Ignores duplicates."`
 	Interval     time.Duration       `default:"15m" help:"Minimum duration between notifications."`
 	Heartbeat    time.Duration       `default:"1m" help:"Duration between checks if there is work to be done."`
 	Probability  float64             `default:"0.50" help:"Starting probability a notification is send after interval."`
 	Delta        float64             `default:"0.1" help:"Delta added to probability on failure to trigger."`
+	Listen       string              `help:"Address to serve /metrics, /healthz and /readyz on. Disabled if empty."`
+	Drain        time.Duration       `default:"30s" help:"Time to wait for in-flight work when shutting down."`
+	Decks        string              `type:"existingfile" help:"YAML file describing decks to serve, each with their own webhooks and schedule. Unset deck settings use the flags."`
+	Threads      string              `default:"none" enum:"none,card,day" help:"Post each card in its own forum post (card) or group a day's cards in one (day). Requires forum channel webhooks."`
+
+	metrics *Metrics `kong:"-"`
+	health  *Health  `kong:"-"`
+	holder  string   `kong:"-"`
 }
 
 func (cmd *CMD) Run(ctx context.Context, logger *slog.Logger) error {
+	if err := cmd.positional(logger); err != nil {
+		return err
+	}
 	return cmd.Config.Run(ctx, logger)
 }
 
+// positional applies `serve <webhook> [database]` from before they were flags
+// so existing invocations and units keep working.
+func (cmd *CMD) positional(logger *slog.Logger) error {
+	if len(cmd.Args) == 0 {
+		return nil
+	} else if len(cmd.Args) > 2 {
+		return fmt.Errorf("expected at most 2 arguments (webhook and database), got %d", len(cmd.Args))
+	}
+	logger.Warn("positional webhook and database are deprecated, use --webhook and --database")
+	cmd.Config.Webhook = cmd.Args[0]
+	if len(cmd.Args) == 2 {
+		cmd.Config.Database = cmd.Args[1]
+	}
+	return nil
+}
+
 func (config *ServerConfig) Run(ctx context.Context, logger *slog.Logger) error {
+	decks, err := config.LoadDecks(ctx)
+	if err != nil {
+		return fmt.Errorf("loading decks: %w", err)
+	}
+
 	db, err := database.Connect(ctx, "sqlite", config.Database)
 	if err != nil {
 		return fmt.Errorf("connecting to database: %w", err)
@@ -52,67 +103,204 @@ func (config *ServerConfig) Run(ctx context.Context, logger *slog.Logger) error
 	}
 	logger.Info("database up", "state", metrics)
 
+	config.metrics = NewMetrics()
+	config.health = &Health{}
+	config.holder = holderID()
+	if config.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", config.metrics.Handler(db, logger))
+		mux.Handle("GET /healthz", HealthzHandler())
+		mux.Handle("GET /readyz", config.ReadyzHandler(db, logger))
+		server := &http.Server{
+			Addr:    config.Listen,
+			Handler: mux,
+		}
+		go func() {
+			logger.Info("serving http", "address", config.Listen)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("serving http", "err", err)
+			}
+		}()
+		defer server.Close()
+	}
+
 	ticker := time.NewTicker(config.Heartbeat)
+	defer ticker.Stop()
+
+	// Work outlives ctx so in-flight ticks can finish while draining.
+	work, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	var inflight sync.WaitGroup
 
 	// Handle any jobs that are ready to run
-	config.TickIfReady(ctx, db, logger)
+	for _, deck := range decks {
+		config.TickIfReady(work, db, deck, logger)
+	}
 
-	slog.Info("starting event loop")
+	if err := Notify("READY=1"); err != nil {
+		logger.Warn("notifying systemd", "err", err)
+	}
+	if interval, err := WatchdogInterval(); err != nil {
+		logger.Warn("reading watchdog interval", "err", err)
+	} else if interval > 0 {
+		go func() {
+			healthy := func() error {
+				return config.health.Ticking(config.maxTickAge())
+			}
+			if err := Watchdog(ctx, interval, healthy); err != nil {
+				logger.Error("running watchdog", "err", err)
+			}
+		}()
+	}
+
+	slog.Info("starting event loop", "decks", len(decks))
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("shutting down", "reason", ctx.Err().Error())
+			if err := Notify("STOPPING=1"); err != nil {
+				logger.Warn("notifying systemd", "err", err)
+			}
+			config.drain(&inflight, cancelWork, logger)
 			return nil
 		case _ = <-ticker.C:
-			go config.TickIfReady(ctx, db, logger)
+			for _, deck := range decks {
+				inflight.Go(func() {
+					config.TickIfReady(work, db, deck, logger)
+				})
+			}
 		}
 	}
 }
 
-func (config *ServerConfig) TickIfReady(ctx context.Context, db *database.Store, logger *slog.Logger) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+// drain waits for in-flight ticks, cancelling them if they take longer than
+// config.Drain.
+func (config *ServerConfig) drain(inflight *sync.WaitGroup, cancel context.CancelFunc, logger *slog.Logger) {
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(config.Drain)
+	defer timer.Stop()
+	select {
+	case <-done:
+		logger.Info("drained in-flight work")
+	case <-timer.C:
+		logger.Warn("cancelling in-flight work", "drain", config.Drain)
+		cancel()
+		<-done
+	}
+}
+
+// TickIfReady runs at most one tick of a deck at a time across this process
+// and any other holding the same database.
+func (config *ServerConfig) TickIfReady(ctx context.Context, db *database.Store, deck *Deck, logger *slog.Logger) {
+	logger = logger.With("job", "work", "deck", deck.Name)
+	if !deck.ticking.TryLock() {
+		logger.Debug("skipping tick (previous tick still running)")
+		return
+	}
+	defer deck.ticking.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, TickTimeout)
 	defer cancel()
-	logger = logger.With("job", "work")
+	config.metrics.Tick(deck.Name)
+	config.health.Tick()
 
-	if err := config.ready(ctx, db, logger); err != nil {
-		if !errors.Is(ErrSkip, err) {
+	if held, err := db.TryLease(ctx, deck.lease(), config.holder, LeaseTTL); err != nil {
+		logger.Error("acquiring lease", "err", err)
+		return
+	} else if !held {
+		logger.Debug("skipping tick (lease held elsewhere)")
+		return
+	}
+	defer func() {
+		ctx, cancel := writeContext(ctx)
+		defer cancel()
+		if err := db.ReleaseLease(ctx, database.ReleaseLeaseParams{
+			Name:   deck.lease(),
+			Holder: config.holder,
+		}); err != nil {
+			logger.Warn("releasing lease", "err", err)
+		}
+	}()
+
+	if err := config.ready(ctx, db, deck, logger); err != nil {
+		if !errors.Is(err, ErrSkip) {
 			logger.Error("determining if ready", "err", err)
 		}
 		return
 	}
 
-	if err := config.Tick(ctx, db, logger); err != nil {
+	if err := config.Tick(ctx, db, deck, logger); err != nil {
 		logger.Error("error doing work", "err", err)
 	}
 }
-func (config *ServerConfig) Tick(ctx context.Context, db *database.Store, logger *slog.Logger) error {
+func (config *ServerConfig) Tick(ctx context.Context, db *database.Store, deck *Deck, logger *slog.Logger) error {
 	// Pick a card.
-	cards, err := db.GetCards(ctx)
+	cards, err := db.Flashcards(ctx)
 	if err != nil {
 		return fmt.Errorf("getting cards: %w", err)
 	}
+	cards = deck.Filter.Apply(cards)
+	if len(cards) == 0 {
+		return errors.New("no cards match the deck filter")
+	}
 
 	// Do the notification stuff
-	selected := database.ConvertFlashcard(cards[rand.Int()%len(cards)])
-	embed := notify.Embed(selected, config.EmbedOptions)
-	if err := embed.Post(config.Webhook); err != nil {
+	selected := cards[rand.Int()%len(cards)]
+	opts := deck.EmbedOptions()
+	// Decks can share the flag's mentions, so don't append to them in place.
+	opts.Mentions = slices.Clone(opts.Mentions)
+	roles, err := db.GetDeckRoles(ctx, deck.Name)
+	if err != nil {
+		return fmt.Errorf("getting roles: %w", err)
+	}
+	for _, role := range roles {
+		opts.Mentions = append(opts.Mentions, discord.Mention{Type: discord.MentionRole, ID: role}.String())
+	}
+	embed, err := notify.Embed(ctx, selected, opts)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, webhook := range deck.Webhooks {
+		start := time.Now()
+		err := config.post(ctx, db, deck, embed, webhook)
+		config.metrics.Send(deck.Name, time.Since(start), err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err = errors.Join(errs...)
+	config.health.Send(deck.Name, err)
+	if len(errs) == len(deck.Webhooks) {
 		return fmt.Errorf("could not post embed: %v: %w", embed, err)
+	} else if err != nil {
+		logger.Warn("could not post embed to every webhook", "err", err)
 	}
 
-	// Put a new job
-	// TODO: We don't want this operation to timeout
-	job, err := db.PutJob(context.TODO(), 0)
+	// Put a new job. The card was already sent so this must not be cut short.
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	job, err := db.PutJob(writeCtx, database.PutJobParams{
+		Deck:     deck.Name,
+		Failures: 0,
+	})
 	if err != nil {
 		// This one is really bad since we might start thrashing and always send response
-		return fmt.Errorf("inserting job", "err", err)
+		return fmt.Errorf("inserting job: %w", err)
 	}
+	config.metrics.FailureStreak(deck.Name, 0)
 	logger.Info("inserted", "job", job)
 	return nil
 }
 
 // Return nil: Ready, ErrSkip: Not Ready, Err: Actual error
-func (config *ServerConfig) ready(ctx context.Context, db *database.Store, logger *slog.Logger) error {
-	jobs, err := db.GetLastJob(ctx)
+func (config *ServerConfig) ready(ctx context.Context, db *database.Store, deck *Deck, logger *slog.Logger) error {
+	jobs, err := db.GetLastJob(ctx, deck.Name)
 	if err != nil {
 		return fmt.Errorf("getting last job: %w", err)
 	} else if len(jobs) == 0 {
@@ -120,23 +308,45 @@ func (config *ServerConfig) ready(ctx context.Context, db *database.Store, logge
 	}
 
 	job := jobs[0]
-	if time.Since(job.CreatedAt) < config.Interval {
+	config.metrics.FailureStreak(deck.Name, job.Failures)
+	if time.Since(job.CreatedAt) < deck.Interval {
 		logger.Debug("not ready (time)")
 		return ErrSkip
 	}
 
-	target := config.Probability + (config.Delta * float64(job.Failures))
+	target := *deck.Probability + (*deck.Delta * float64(job.Failures))
 	roll := rand.Float64()
 	logger.Info("rolling", "target", target, "roll", roll, "status", roll >= target, "job", job)
+	config.metrics.Roll(deck.Name, roll < target)
 	if roll >= target {
 		return nil
 	}
 
 	// Log that we failed
-	// TODO: We don't want this operation to timeout
-	_, err = db.PutJob(context.TODO(), job.Failures+1)
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err = db.PutJob(writeCtx, database.PutJobParams{
+		Deck:     deck.Name,
+		Failures: job.Failures + 1,
+	})
 	if err != nil {
 		return err
 	}
+	config.metrics.FailureStreak(deck.Name, job.Failures+1)
 	return ErrSkip
 }
+
+// writeContext detaches ctx from cancellation so a write started during a tick
+// still lands if the tick times out or the server shuts down.
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), WriteTimeout)
+}
+
+// holderID identifies this process when taking leases.
+func holderID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return hostname + ":" + strconv.Itoa(os.Getpid())
+}
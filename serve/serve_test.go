@@ -0,0 +1,214 @@
+package serve
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/flashcard"
+)
+
+var discard = slog.New(slog.DiscardHandler)
+
+// webhook counts posts, taking delay to respond so ticks overlap.
+func webhook(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var posts atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "1", "channel_id": "2"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &posts
+}
+
+// open connects to the database at path, as a separate process would.
+func open(t *testing.T, path string) *database.Store {
+	t.Helper()
+	db, err := database.Connect(context.Background(), "sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// connect opens the database at path with a card to send.
+func connect(t *testing.T, path string) *database.Store {
+	t.Helper()
+	db := open(t, path)
+	if _, err := db.LoadFlashcards(context.Background(), []flashcard.Flashcard{{
+		Header:      "Mercury",
+		Description: "The first planet from the Sun.",
+		Origin:      "[wikipedia](https://en.wikipedia.org/wiki/Mercury_(planet))",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func testServer(holder string, webhook string) (*ServerConfig, *Deck) {
+	config := &ServerConfig{
+		Interval:    time.Hour,
+		Heartbeat:   time.Minute,
+		Probability: 1,
+		Drain:       time.Second,
+		metrics:     NewMetrics(),
+		health:      &Health{},
+		holder:      holder,
+	}
+	return config, config.defaults(&Deck{Webhooks: []string{webhook}})
+}
+
+func TestPositional(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Args     []string
+		Webhook  string
+		Database string
+		Err      bool
+	}{
+		{Name: "flags", Args: []string{"--webhook", "https://hook", "--database", "cards.db"}, Webhook: "https://hook", Database: "cards.db"},
+		{Name: "webhook", Args: []string{"https://hook"}, Webhook: "https://hook", Database: "fishy.db"},
+		{Name: "webhook and database", Args: []string{"https://hook", "cards.db"}, Webhook: "https://hook", Database: "cards.db"},
+		{Name: "too many", Args: []string{"https://hook", "cards.db", "extra"}, Err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			for _, env := range []string{"FISHY_WEBHOOK", "FISHY_DATABASE"} {
+				t.Setenv(env, "")
+				os.Unsetenv(env)
+			}
+			var cmd CMD
+			parser, err := kong.New(&cmd)
+			if err != nil {
+				t.Fatal(err)
+			} else if _, err := parser.Parse(test.Args); err != nil {
+				t.Fatal(err)
+			}
+			err = cmd.positional(discard)
+			if (err != nil) != test.Err {
+				t.Fatalf("got error %v, want error: %v", err, test.Err)
+			} else if err == nil && (cmd.Config.Webhook != test.Webhook || cmd.Config.Database != test.Database) {
+				t.Errorf("got webhook %q and database %q, want %q and %q", cmd.Config.Webhook, cmd.Config.Database, test.Webhook, test.Database)
+			}
+		})
+	}
+}
+
+func TestTickIfReadyOverlapping(t *testing.T) {
+	server, posts := webhook(t, 100*time.Millisecond)
+	db := connect(t, filepath.Join(t.TempDir(), "fishy.db"))
+	config, deck := testServer("a", server.URL)
+
+	// Heartbeats that fire while a slow tick is still posting.
+	var heartbeats sync.WaitGroup
+	for range 5 {
+		heartbeats.Go(func() {
+			config.TickIfReady(context.Background(), db, deck, discard)
+		})
+	}
+	heartbeats.Wait()
+
+	if got := posts.Load(); got != 1 {
+		t.Errorf("posted %d times, want 1", got)
+	}
+}
+
+func TestTickIfReadySharedDatabase(t *testing.T) {
+	server, posts := webhook(t, 100*time.Millisecond)
+	path := filepath.Join(t.TempDir(), "fishy.db")
+	connect(t, path)
+
+	// Replicas each have their own connection and decks.
+	var replicas sync.WaitGroup
+	for _, holder := range []string{"a", "b", "c"} {
+		db := open(t, path)
+		config, deck := testServer(holder, server.URL)
+		replicas.Go(func() {
+			config.TickIfReady(context.Background(), db, deck, discard)
+		})
+	}
+	replicas.Wait()
+
+	if got := posts.Load(); got != 1 {
+		t.Errorf("posted %d times, want 1", got)
+	}
+}
+
+func TestTickIfReadyLeaseHeld(t *testing.T) {
+	server, posts := webhook(t, 0)
+	db := connect(t, filepath.Join(t.TempDir(), "fishy.db"))
+	config, deck := testServer("a", server.URL)
+
+	ctx := context.Background()
+	if held, err := db.TryLease(ctx, deck.lease(), "b", LeaseTTL); err != nil || !held {
+		t.Fatalf("taking lease: %v %v", held, err)
+	}
+	config.TickIfReady(ctx, db, deck, discard)
+	if got := posts.Load(); got != 0 {
+		t.Errorf("posted %d times while another holder has the lease, want 0", got)
+	}
+
+	if err := db.ReleaseLease(ctx, database.ReleaseLeaseParams{Name: deck.lease(), Holder: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	config.TickIfReady(ctx, db, deck, discard)
+	if got := posts.Load(); got != 1 {
+		t.Errorf("posted %d times after the lease was released, want 1", got)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("waits", func(t *testing.T) {
+		config := &ServerConfig{Drain: time.Second}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var inflight sync.WaitGroup
+		var finished atomic.Bool
+		inflight.Go(func() {
+			time.Sleep(50 * time.Millisecond)
+			finished.Store(true)
+		})
+		config.drain(&inflight, cancel, discard)
+
+		if !finished.Load() {
+			t.Error("drain returned before in-flight work finished")
+		} else if ctx.Err() != nil {
+			t.Error("drain cancelled work that finished in time")
+		}
+	})
+
+	t.Run("cancels", func(t *testing.T) {
+		config := &ServerConfig{Drain: 50 * time.Millisecond}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var inflight sync.WaitGroup
+		var finished atomic.Bool
+		inflight.Go(func() {
+			<-ctx.Done()
+			finished.Store(true)
+		})
+		start := time.Now()
+		config.drain(&inflight, cancel, discard)
+
+		if !finished.Load() {
+			t.Error("drain returned before cancelled work finished")
+		} else if elapsed := time.Since(start); elapsed < config.Drain {
+			t.Errorf("drain cancelled work after %s, want at least %s", elapsed, config.Drain)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state (ex: "READY=1") to the service manager using the
+// sd_notify protocol. It does nothing when NOTIFY_SOCKET is unset.
+func Notify(state string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	// Abstract sockets are passed with a leading '@'.
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dialing notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("writing to notify socket: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns how often the service manager expects a
+// "WATCHDOG=1" notification, or 0 if the watchdog is disabled.
+func WatchdogInterval() (time.Duration, error) {
+	value := os.Getenv("WATCHDOG_USEC")
+	if value == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC: %q", value)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// Watchdog pings the service manager at half the interval it expects for as
+// long as healthy returns nil.
+func Watchdog(ctx context.Context, interval time.Duration, healthy func() error) error {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := healthy(); err != nil {
+				// Let the service manager notice we are stuck and restart us.
+				continue
+			}
+			if err := Notify("WATCHDOG=1"); err != nil {
+				return err
+			}
+		}
+	}
+}
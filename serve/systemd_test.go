@@ -0,0 +1,119 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// notifySocket listens on a fake NOTIFY_SOCKET, returning the states sent to
+// it so far.
+func notifySocket(t *testing.T) func() []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	var states []string
+	return func() []string {
+		buffer := make([]byte, 1024)
+		for {
+			conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, err := conn.Read(buffer)
+			if err != nil {
+				return states
+			}
+			states = append(states, string(buffer[:n]))
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	states := notifySocket(t)
+	if err := Notify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := states(); !slices.Equal(got, []string{"READY=1"}) {
+		t.Errorf("got %q, want [READY=1]", got)
+	}
+}
+
+func TestNotifyUnset(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify("READY=1"); err != nil {
+		t.Errorf("got %v, want nil without NOTIFY_SOCKET", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Usec     string
+		PID      string
+		Expected time.Duration
+		Err      bool
+	}{
+		{Name: "disabled"},
+		{Name: "enabled", Usec: "2000000", Expected: 2 * time.Second},
+		{Name: "this process", Usec: "2000000", PID: strconv.Itoa(os.Getpid()), Expected: 2 * time.Second},
+		{Name: "other process", Usec: "2000000", PID: "1"},
+		{Name: "invalid", Usec: "soon", Err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", test.Usec)
+			t.Setenv("WATCHDOG_PID", test.PID)
+			interval, err := WatchdogInterval()
+			if (err != nil) != test.Err {
+				t.Fatalf("got error %v, want error: %v", err, test.Err)
+			} else if interval != test.Expected {
+				t.Errorf("got %s, want %s", interval, test.Expected)
+			}
+		})
+	}
+}
+
+// TestRunNotifies checks the server tells systemd it started, is alive and is
+// stopping.
+func TestRunNotifies(t *testing.T) {
+	states := notifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	server, _ := webhook(t, 0)
+	path := filepath.Join(t.TempDir(), "fishy.db")
+	connect(t, path)
+	config := &ServerConfig{
+		Webhook:     server.URL,
+		Database:    path,
+		Interval:    time.Hour,
+		Heartbeat:   time.Hour,
+		Probability: 1,
+		Drain:       time.Second,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := config.Run(ctx, discard); err != nil {
+		t.Fatal(err)
+	}
+
+	got := states()
+	for _, state := range []string{"READY=1", "WATCHDOG=1", "STOPPING=1"} {
+		if !slices.Contains(got, state) {
+			t.Errorf("%s not sent: got %q", state, got)
+		}
+	}
+	if ready, stopping := slices.Index(got, "READY=1"), slices.Index(got, "STOPPING=1"); ready > stopping {
+		t.Errorf("STOPPING=1 sent before READY=1: got %q", got)
+	}
+}
@@ -0,0 +1,94 @@
+package serve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/discord"
+	"github.com/ohhfishal/fishy/notify"
+)
+
+// How cards are threaded. Threads are only created when the webhook is for a
+// forum channel.
+const (
+	// ThreadsNone posts cards straight to the webhook's channel.
+	ThreadsNone = "none"
+	// ThreadsCard creates a forum post for each card, named after it.
+	ThreadsCard = "card"
+	// ThreadsDay groups a day's cards in one forum post.
+	ThreadsDay = "day"
+)
+
+// post sends embed to webhook, creating or reusing a thread for it as the
+// deck's Threads setting asks.
+func (config *ServerConfig) post(ctx context.Context, db *database.Store, deck *Deck, embed discord.Embed, webhook string) error {
+	switch deck.Threads {
+	case ThreadsCard:
+		if len(embed.Messages) > 0 {
+			embed.ThreadName = notify.Truncate(embed.Messages[0].Title, discord.MaxThreadName)
+		}
+		return embed.Post(webhook)
+	case ThreadsDay:
+		return config.postDaily(ctx, db, deck, embed, webhook)
+	default:
+		return embed.Post(webhook)
+	}
+}
+
+func (config *ServerConfig) postDaily(ctx context.Context, db *database.Store, deck *Deck, embed discord.Embed, webhook string) error {
+	day := time.Now().Format(time.DateOnly)
+	key := hashWebhook(webhook)
+	threads, err := db.GetThread(ctx, database.GetThreadParams{
+		Deck:    deck.Name,
+		Webhook: key,
+		Day:     day,
+	})
+	if err != nil {
+		return fmt.Errorf("getting thread: %w", err)
+	}
+	if len(threads) > 0 {
+		embed.ThreadID = threads[0]
+		err := embed.Post(webhook)
+		var response *discord.ResponseError
+		if !errors.As(err, &response) || (response.StatusCode != http.StatusNotFound && response.Code() != discord.CodeUnknownChannel) {
+			return err
+		}
+		// The thread was deleted. Start a new one.
+		embed.ThreadID = ""
+	}
+
+	name := deck.Name
+	if name == "" {
+		name = "Flashcards"
+	}
+	embed.ThreadName = notify.Truncate(name+" "+day, discord.MaxThreadName)
+	posted, err := embed.Send(webhook)
+	if err != nil {
+		return err
+	}
+
+	// The card was already sent so this must not be cut short.
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+	if err := db.PutThread(writeCtx, database.PutThreadParams{
+		Deck:     deck.Name,
+		Webhook:  key,
+		Day:      day,
+		ThreadID: posted.ChannelID,
+	}); err != nil {
+		return fmt.Errorf("saving thread: %w", err)
+	}
+	return nil
+}
+
+// hashWebhook identifies webhook without storing the secret.
+func hashWebhook(webhook string) string {
+	sum := sha256.Sum256([]byte(webhook))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ohhfishal/fishy/database"
+	"github.com/ohhfishal/fishy/discord"
+)
+
+// TestPostDaily checks a stored thread is only replaced when Discord says it
+// no longer exists.
+func TestPostDaily(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Status   int
+		Body     string
+		Expected []string
+		Err      bool
+	}{
+		{Name: "exists", Status: http.StatusOK, Expected: []string{"old"}},
+		{Name: "not found", Status: http.StatusNotFound, Body: `{"message": "Unknown Channel", "code": 10003}`, Expected: []string{"old", ""}},
+		{Name: "unknown channel", Status: http.StatusBadRequest, Body: `{"message": "Unknown Channel", "code": 10003}`, Expected: []string{"old", ""}},
+		{Name: "invalid form", Status: http.StatusBadRequest, Body: `{"message": "Invalid Form Body", "code": 50035}`, Expected: []string{"old"}, Err: true},
+		{Name: "forbidden", Status: http.StatusForbidden, Body: `{"message": "Missing Access", "code": 50001}`, Expected: []string{"old"}, Err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var threads []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				thread := r.URL.Query().Get("thread_id")
+				threads = append(threads, thread)
+				if thread != "" && test.Status != http.StatusOK {
+					http.Error(w, test.Body, test.Status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id": "1", "channel_id": "new"}`))
+			}))
+			t.Cleanup(server.Close)
+
+			ctx := context.Background()
+			db := connect(t, filepath.Join(t.TempDir(), "fishy.db"))
+			config, deck := testServer("a", server.URL)
+			deck.Threads = ThreadsDay
+			day := time.Now().Format(time.DateOnly)
+			if err := db.PutThread(ctx, database.PutThreadParams{
+				Webhook:  hashWebhook(server.URL),
+				Day:      day,
+				ThreadID: "old",
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			embed := discord.Embed{Messages: []discord.Message{{Title: "Mercury"}}}
+			err := config.post(ctx, db, deck, embed, server.URL)
+			if (err != nil) != test.Err {
+				t.Fatalf("got error %v, want error: %v", err, test.Err)
+			} else if !slices.Equal(threads, test.Expected) {
+				t.Errorf("posted to threads %q, want %q", threads, test.Expected)
+			}
+
+			stored, err := db.GetThread(ctx, database.GetThreadParams{Webhook: hashWebhook(server.URL), Day: day})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "old"
+			if len(test.Expected) > 1 {
+				want = "new"
+			}
+			if len(stored) != 1 || stored[0] != want {
+				t.Errorf("got stored threads %q, want %s", stored, want)
+			}
+		})
+	}
+}